@@ -28,6 +28,7 @@ mkdir "%OUT_DIR%"
 rem 拷贝文件
 rem 隐藏无用输出：> nul（标准输出），2> nul（错误输出）
 copy /Y "config.ini" "%OUT_DIR%\" > nul
+copy /Y "metric.ini" "%OUT_DIR%\" > nul
 
 rem 构建
 echo BUILDING ...
@@ -0,0 +1,107 @@
+// @author xiangqian
+// @date 2025/08/16 11:05
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"gmon/pkg/user"
+	"golang.org/x/term"
+	pkg_ini "gopkg.in/ini.v1"
+	"os"
+	"strings"
+)
+
+// 添加用户或修改用户密码
+// 用法：gmon add-user [-file users.ini] <用户名>
+// 标准输入为终端时提示输入密码（不回显），否则从标准输入读取一行作为密码，便于脚本调用
+func addUser(args []string) error {
+	fs := flag.NewFlagSet("add-user", flag.ContinueOnError)
+	file := fs.String("file", usersFile(), "users file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gmon add-user [-file users.ini] <name>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("user name is required")
+	}
+	name := fs.Arg(0)
+
+	passwd, err := readPasswd()
+	if err != nil {
+		return err
+	}
+
+	err = user.Add(*file, name, passwd)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("user %s saved to %s\n", name, *file)
+	return nil
+}
+
+// 读取密码
+func readPasswd() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("read password: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Print("Password: ")
+	passwd, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	fmt.Print("Confirm password: ")
+	confirm, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if string(passwd) != string(confirm) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+	return string(passwd), nil
+}
+
+// 配置文件中的用户文件，未配置时为 users.ini
+func usersFile() string {
+	file, err := pkg_ini.LooseLoad(configFile)
+	if err != nil {
+		return "users.ini"
+	}
+	applyOverrides(file)
+	return strings.TrimSpace(file.Section("http").Key("users").MustString("users.ini"))
+}
+
+// 校验配置文件，报告所有问题，配置有效时退出码为 0，便于在 CI 中使用
+// 用法：gmon [-config config.ini] check-config
+func checkConfig(args []string) error {
+	fs := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gmon [-config config.ini] check-config")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := LoadConfig(configFile)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: ok\n", configFile)
+	return nil
+}
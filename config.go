@@ -3,53 +3,377 @@
 package main
 
 import (
+	"crypto/tls"
+	"fmt"
+	"gmon/pkg/alert"
+	"gmon/pkg/notify"
 	"gmon/pkg/prom"
+	"gmon/pkg/sso"
+	"gmon/pkg/user"
+	"gmon/pkg/xhttp"
 	pkg_ini "gopkg.in/ini.v1"
+	pkg_http "net/http"
+	"os"
 	"strings"
+	"time"
 )
 
-// LoadConfig 加载配置文件
-func LoadConfig() (Config, error) {
-	file, err := pkg_ini.Load("config.ini")
+// 配置文件，可以通过命令行参数 -config 或环境变量 GMON_CONFIG 指定
+var configFile = "config.ini"
+
+// LoadConfig 加载配置文件，只在文件无法读取或格式错误时返回错误；
+// 无效的配置项使用默认值，由 Config.Validate 报告，以便一次报告所有问题
+func LoadConfig(name string) (Config, error) {
+	data, err := os.ReadFile(name)
 	if err != nil {
 		return Config{}, err
 	}
-
-	// http
-	section, err := file.GetSection("http")
+	file, err := pkg_ini.Load(data)
 	if err != nil {
-		return Config{}, err
+		return Config{}, fmt.Errorf("%s: %v", name, err)
 	}
+	var loc = newLocator(name, data)
+	loc.sources = applyOverrides(file)
+
+	// 小节、配置项及其值的格式，在读取配置项前校验（读取时无效的值会被替换为默认值），由 Validate 报告
+	keyErr := checkKeys(file, loc)
+
+	// http
+	section := file.Section("http")
 	var http = Http{
+		Host:   strings.TrimSpace(section.Key("host").String()),
 		Port:   uint16(section.Key("port").MustUint()),
 		Prefix: strings.TrimSpace(section.Key("prefix").String()),
-		User:   strings.TrimSpace(section.Key("user").String()),
-		Passwd: strings.TrimSpace(section.Key("passwd").String()),
+		Users:  strings.TrimSpace(section.Key("users").MustString("users.ini")),
+
+		ReadTimeout:       section.Key("read_timeout").MustDuration(30 * time.Second),
+		ReadHeaderTimeout: section.Key("read_header_timeout").MustDuration(10 * time.Second),
+		WriteTimeout:      section.Key("write_timeout").MustDuration(60 * time.Second),
+		IdleTimeout:       section.Key("idle_timeout").MustDuration(2 * time.Minute),
+		ShutdownTimeout:   section.Key("shutdown_timeout").MustDuration(10 * time.Second),
 	}
 
+	// tls
+	http.Tls = loadTls(section)
+
+	// role
+	http.Roles = loadRoles(file)
+
+	// login
+	http.Limit = loadLimit(file)
+
+	// oidc
+	http.Oidc = loadOidc(file)
+
+	// proxy
+	http.Proxy = loadProxy(file, http.Roles)
+
+	// api
+	http.Tokens = strings.TrimSpace(file.Section("api").Key("tokens").String())
+
+	// session
+	session := loadSession(file, http.Tls.Enabled())
+	// Cookie 有效路径与 HTTP 请求前缀一致
+	session.CookiePath = http.Prefix
+
 	// prom
-	section, err = file.GetSection("prom")
+	proms := loadProms(file)
+
+	// metric，指标目录文件不存在等问题由 Validate 报告
+	metricFile := strings.TrimSpace(file.Section("prom").Key("metric").MustString("metric.ini"))
+	metrics, _ := LoadMetrics(metricFile)
+
+	// alert
+	alert := loadAlert(file)
+
+	// notify
+	var notify notify.Config
+	if section, err := file.GetSection("webhook"); err == nil {
+		notify.Webhook = loadWebhook(section)
+	}
+	if section, err := file.GetSection("smtp"); err == nil {
+		notify.Smtp = loadSmtp(section)
+	}
+
+	return Config{Http: http, Session: session, Prom: proms, MetricFile: metricFile, Metrics: metrics, Alert: alert, Notify: notify, keyErr: keyErr, locator: loc}, nil
+}
+
+// 加载用户角色配置：[role] 小节中每个键为用户名，值为角色，无效的角色由 Validate 报告
+func loadRoles(file *pkg_ini.File) map[string]xhttp.Role {
+	var roles = make(map[string]xhttp.Role)
+	section, err := file.GetSection("role")
 	if err != nil {
-		return Config{}, err
+		return roles
+	}
+	for _, key := range section.Keys() {
+		if role, err := xhttp.ParseRole(key.String()); err == nil {
+			roles[key.Name()] = role
+		}
+	}
+	return roles
+}
+
+// 加载登录失败限制配置
+func loadLimit(file *pkg_ini.File) user.LimitConfig {
+	var config = user.LimitConfig{
+		MaxFailures: 5,
+		Delay:       time.Second,
+		MaxDelay:    30 * time.Second,
+		Lockout:     15 * time.Minute,
+	}
+	section, err := file.GetSection("login")
+	if err != nil {
+		return config
+	}
+
+	config.MaxFailures = section.Key("max_failures").MustInt(config.MaxFailures)
+	config.Delay = section.Key("delay").MustDuration(config.Delay)
+	config.MaxDelay = section.Key("max_delay").MustDuration(config.MaxDelay)
+	config.Lockout = section.Key("lockout").MustDuration(config.Lockout)
+	return config
+}
+
+// 加载单点登录配置
+func loadOidc(file *pkg_ini.File) sso.Config {
+	section, err := file.GetSection("oidc")
+	if err != nil {
+		return sso.Config{}
+	}
+	return sso.Config{
+		Name:         strings.TrimSpace(section.Key("name").String()),
+		Issuer:       strings.TrimSpace(section.Key("issuer").String()),
+		ClientId:     strings.TrimSpace(section.Key("client_id").String()),
+		ClientSecret: strings.TrimSpace(section.Key("client_secret").String()),
+		RedirectUrl:  strings.TrimSpace(section.Key("redirect_url").String()),
+		Scopes:       section.Key("scopes").Strings(","),
+		UserClaim:    strings.TrimSpace(section.Key("user_claim").String()),
+		RoleClaim:    strings.TrimSpace(section.Key("role_claim").String()),
+		AdminGroups:  section.Key("admin_groups").Strings(","),
+		ViewerGroups: section.Key("viewer_groups").Strings(","),
+	}
+}
+
+// 加载反向代理认证配置
+func loadProxy(file *pkg_ini.File, roles map[string]xhttp.Role) xhttp.ProxyConfig {
+	var config = xhttp.ProxyConfig{Roles: roles}
+	section, err := file.GetSection("proxy")
+	if err != nil {
+		return config
+	}
+
+	config.Header = strings.TrimSpace(section.Key("header").String())
+	config.Cidrs, _ = xhttp.ParseCidrs(section.Key("cidrs").Strings(","))
+	return config
+}
+
+// 加载会话配置
+func loadSession(file *pkg_ini.File, https bool) xhttp.Config {
+	var config = xhttp.Config{
+		Store:    "memory",
+		Dir:      "sessions",
+		MaxAge:   12 * time.Hour,
+		MaxCount: 100,
+		// 启用 HTTPS 时 Cookie 默认只通过 HTTPS 发送
+		CookieSecure:   https,
+		CookieSameSite: pkg_http.SameSiteLaxMode,
+	}
+	section, err := file.GetSection("session")
+	if err != nil {
+		return config
+	}
+
+	config.Store = strings.TrimSpace(section.Key("store").MustString(config.Store))
+	config.Dir = strings.TrimSpace(section.Key("dir").MustString(config.Dir))
+	config.MaxAge = section.Key("max_age").MustDuration(config.MaxAge)
+	config.MaxCount = section.Key("max_count").MustInt(config.MaxCount)
+	config.CookieSecure = section.Key("cookie_secure").MustBool(config.CookieSecure)
+	switch strings.ToLower(strings.TrimSpace(section.Key("cookie_same_site").String())) {
+	case "strict":
+		config.CookieSameSite = pkg_http.SameSiteStrictMode
+	case "none":
+		config.CookieSameSite = pkg_http.SameSiteNoneMode
+	}
+	return config
+}
+
+// 加载 HTTPS 配置：[http] 小节中的 tls_* 配置项
+func loadTls(section *pkg_ini.Section) xhttp.TlsConfig {
+	var config = xhttp.TlsConfig{
+		Cert:         strings.TrimSpace(section.Key("tls_cert").String()),
+		Key:          strings.TrimSpace(section.Key("tls_key").String()),
+		ClientCa:     strings.TrimSpace(section.Key("tls_client_ca").String()),
+		RedirectPort: uint16(section.Key("tls_redirect_port").MustUint()),
+		MinVersion:   tls.VersionTLS12,
+	}
+	if version, err := xhttp.ParseTlsVersion(strings.TrimSpace(section.Key("tls_min_version").String())); err == nil {
+		config.MinVersion = version
+	}
+	return config
+}
+
+// 加载 Prometheus 数据源配置：
+// 存在 [prom.<name>] 小节时，每个小节为一个数据源，[prom] 小节中的配置作为各数据源的默认配置；
+// 否则 [prom] 小节为唯一的数据源，名称为 default
+func loadProms(file *pkg_ini.File) []prom.Config {
+	var proms []prom.Config
+	for _, section := range file.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), "prom.")
+		if !ok {
+			continue
+		}
+		proms = append(proms, loadProm(name, section))
+	}
+
+	if len(proms) == 0 {
+		if section, err := file.GetSection("prom"); err == nil {
+			proms = append(proms, loadProm("default", section))
+		}
+	}
+	return proms
+}
+
+// 加载 Prometheus 数据源配置
+func loadProm(name string, section *pkg_ini.Section) prom.Config {
+	return prom.Config{
+		Name:   name,
+		Scheme: strings.TrimSpace(section.Key("scheme").MustString("http")),
+		Host:   strings.TrimSpace(section.Key("host").String()),
+		Port:   uint16(section.Key("port").MustUint()),
+		Path:   strings.TrimSpace(section.Key("path").String()),
+
+		CaFile:             strings.TrimSpace(section.Key("ca_file").String()),
+		CertFile:           strings.TrimSpace(section.Key("cert_file").String()),
+		KeyFile:            strings.TrimSpace(section.Key("key_file").String()),
+		InsecureSkipVerify: section.Key("insecure_skip_verify").MustBool(false),
+
+		User:      strings.TrimSpace(section.Key("user").String()),
+		Passwd:    strings.TrimSpace(section.Key("passwd").String()),
+		Token:     strings.TrimSpace(section.Key("token").String()),
+		TokenFile: strings.TrimSpace(section.Key("token_file").String()),
 	}
-	var prom = prom.Config{
-		Host: strings.TrimSpace(section.Key("host").String()),
-		Port: uint16(section.Key("port").MustUint()),
+}
+
+// 加载邮件配置
+func loadSmtp(section *pkg_ini.Section) notify.SmtpConfig {
+	var to []string
+	for _, addr := range section.Key("to").Strings(",") {
+		if addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return notify.SmtpConfig{
+		Host:     strings.TrimSpace(section.Key("host").String()),
+		Port:     uint16(section.Key("port").MustUint(25)),
+		Tls:      strings.TrimSpace(section.Key("tls").MustString(notify.TlsNone)),
+		User:     strings.TrimSpace(section.Key("user").String()),
+		Passwd:   strings.TrimSpace(section.Key("passwd").String()),
+		From:     strings.TrimSpace(section.Key("from").String()),
+		To:       to,
+		Interval: section.Key("interval").MustDuration(10 * time.Minute),
+		Timeout:  section.Key("timeout").MustDuration(10 * time.Second),
 	}
+}
 
-	return Config{Http: http, Prom: prom}, nil
+// 加载 Webhook 配置
+func loadWebhook(section *pkg_ini.Section) notify.WebhookConfig {
+	var urls []string
+	for _, url := range section.Key("urls").Strings(",") {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return notify.WebhookConfig{
+		Urls:    urls,
+		Retries: section.Key("retries").MustInt(3),
+		Backoff: section.Key("backoff").MustDuration(time.Second),
+		Timeout: section.Key("timeout").MustDuration(5 * time.Second),
+	}
+}
+
+// 加载告警配置：[alert] 小节为全局配置，[alert.<name>] 小节为告警规则
+func loadAlert(file *pkg_ini.File) alert.Config {
+	var config alert.Config
+	if section, err := file.GetSection("alert"); err == nil {
+		config.Interval = section.Key("interval").MustDuration(15 * time.Second)
+	}
+
+	for _, section := range file.Sections() {
+		name, ok := strings.CutPrefix(section.Name(), "alert.")
+		if !ok {
+			continue
+		}
+
+		var rule = alert.Rule{
+			Name:   name,
+			Type:   strings.TrimSpace(section.Key("type").String()),
+			Metric: strings.TrimSpace(section.Key("metric").String()),
+			Op:     strings.TrimSpace(section.Key("op").MustString(">")),
+			For:    section.Key("for").MustDuration(0),
+		}
+		rule.Threshold = section.Key("threshold").MustFloat64(0)
+		config.Rules = append(config.Rules, rule)
+	}
+	return config
+}
+
+// LoadMetrics 加载指标目录文件，指标系列的配置由 Config.Validate 校验
+func LoadMetrics(name string) ([]prom.Metric, error) {
+	file, err := pkg_ini.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []prom.Metric
+	for _, section := range file.Sections() {
+		// 跳过默认小节
+		if section.Name() == pkg_ini.DefaultSection {
+			continue
+		}
+
+		var metric = prom.Metric{
+			Job:   strings.TrimSpace(section.Key("job").String()),
+			Name:  strings.TrimSpace(section.Key("name").String()),
+			Expr:  strings.TrimSpace(section.Key("expr").String()),
+			Unit:  strings.TrimSpace(section.Key("unit").String()),
+			Label: strings.TrimSpace(section.Key("label").String()),
+			Axis:  strings.TrimSpace(section.Key("axis").MustString("left")),
+			Chart: strings.TrimSpace(section.Key("chart").MustString("CPU/MEM")),
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
 }
 
 // Config 配置
 type Config struct {
-	Http Http        // HTTP 配置
-	Prom prom.Config // Prometheus 配置
+	Http       Http          // HTTP 配置
+	Session    xhttp.Config  // 会话配置
+	Prom       []prom.Config // Prometheus 数据源配置
+	MetricFile string        // 指标目录文件
+	Metrics    []prom.Metric // 指标目录
+	Alert      alert.Config  // 告警配置
+	Notify     notify.Config // 通知配置
+
+	keyErr  error   // 小节、配置项及其值的格式问题
+	locator locator // 配置项位置，用于校验时报告问题
 }
 
 // Http HTTP 配置
 type Http struct {
-	Port   uint16 // 监听端口
-	Prefix string // HTTP 请求前缀
-	User   string // 登录用户
-	Passwd string // 登录密码（如果含有特殊字符，如 #，则使用反引号括起来）
+	Host   string                // 监听地址，为空时监听所有地址
+	Port   uint16                // 监听端口
+	Prefix string                // HTTP 请求前缀
+	Users  string                // 用户文件
+	Roles  map[string]xhttp.Role // 用户角色：用户名 -> 角色
+	Limit  user.LimitConfig      // 登录失败限制
+	Oidc   sso.Config            // 单点登录
+	Tokens string                // API 令牌文件
+	Proxy  xhttp.ProxyConfig     // 反向代理认证
+	Tls    xhttp.TlsConfig       // HTTPS
+
+	ReadTimeout       time.Duration // 读取请求（包括请求体）超时时间
+	ReadHeaderTimeout time.Duration // 读取请求头超时时间
+	WriteTimeout      time.Duration // 写入响应超时时间，事件流（/event）除外
+	IdleTimeout       time.Duration // 空闲连接（keep-alive）超时时间
+	ShutdownTimeout   time.Duration // 关闭服务器时等待处理中的请求完成的最长时间
 }
@@ -0,0 +1,27 @@
+// @author xiangqian
+// @date 2025/08/02 11:05
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMetrics(t *testing.T) {
+	metrics, err := LoadMetrics("metric.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) == 0 {
+		t.Fatal("empty metric catalog")
+	}
+	for _, metric := range metrics {
+		expr := metric.LabelExpr()
+		if strings.Contains(expr, "$job") {
+			t.Errorf("%s.%s: unreplaced $job in %s", metric.Job, metric.Name, expr)
+		}
+		if !strings.Contains(expr, `"name", "`+metric.Name+`"`) {
+			t.Errorf("%s.%s: missing name label in %s", metric.Job, metric.Name, expr)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// @author xiangqian
+// @date 2025/09/05 19:48
+package main
+
+import (
+	"flag"
+	"fmt"
+	pkg_ini "gopkg.in/ini.v1"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// 命令行参数和环境变量：同一个二进制文件在容器和物理机上运行时，无需修改配置文件。
+// [http] 和 [prom] 小节中的每个配置项都可以通过环境变量 GMON_<小节>_<配置项> 覆盖，如 GMON_HTTP_PORT、GMON_PROM_HOST，
+// 常用配置项还可以通过命令行参数覆盖。优先级（从高到低）：命令行参数、环境变量、配置文件、默认值。
+// [prom] 小节中的配置同时作为 [prom.<数据源名称>] 小节的默认配置，数据源小节中已配置的项不会被覆盖
+
+// 配置文件环境变量
+const configEnv = "GMON_CONFIG"
+
+// 可以通过环境变量覆盖的配置项：小节 -> 配置项
+var overrideKeys = []struct {
+	section string
+	keys    []string
+}{
+	{"http", []string{"host", "port", "prefix", "users",
+		"read_timeout", "read_header_timeout", "write_timeout", "idle_timeout", "shutdown_timeout",
+		"tls_cert", "tls_key", "tls_min_version", "tls_client_ca", "tls_redirect_port"}},
+	{"prom", []string{"scheme", "host", "port", "path", "metric",
+		"ca_file", "cert_file", "key_file", "insecure_skip_verify",
+		"user", "passwd", "token", "token_file"}},
+}
+
+// 命令行参数覆盖的配置项：小节 -> 配置项 -> 值
+var flagOverrides = make(map[string]map[string]string)
+
+// 解析命令行参数，返回剩余参数（子命令及其参数）
+// 用法：gmon [-config config.ini] [-listen [host]:port] [-prom-url url] ... [command]
+func parseFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("gmon", flag.ContinueOnError)
+	configFlag := fs.String("config", "", fmt.Sprintf("config file (env %s, default %s)", configEnv, configFile))
+	fs.Func("listen", "listen address [host]:port, overrides [http] host and port", func(s string) error {
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return err
+		}
+		setOverride("http", "host", host)
+		setOverride("http", "port", port)
+		return nil
+	})
+	fs.Func("prefix", "http request prefix, overrides [http] prefix", func(s string) error {
+		setOverride("http", "prefix", s)
+		return nil
+	})
+	fs.Func("users", "users file, overrides [http] users", func(s string) error {
+		setOverride("http", "users", s)
+		return nil
+	})
+	fs.Func("tls-cert", "tls certificate file, overrides [http] tls_cert", func(s string) error {
+		setOverride("http", "tls_cert", s)
+		return nil
+	})
+	fs.Func("tls-key", "tls private key file, overrides [http] tls_key", func(s string) error {
+		setOverride("http", "tls_key", s)
+		return nil
+	})
+	fs.Func("prom-url", "prometheus url scheme://[user:passwd@]host[:port][/path], overrides [prom] scheme, host, port, path (and user, passwd)", parsePromUrl)
+	fs.Func("metric", "metric catalog file, overrides [prom] metric", func(s string) error {
+		setOverride("prom", "metric", s)
+		return nil
+	})
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gmon [flags] [command]")
+		fmt.Fprintln(fs.Output(), "\nCommands:\n  add-user\tadd a user or change the password of a user\n  check-config\tvalidate the config file and report all problems")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nEnvironment:\n  GMON_<SECTION>_<KEY>, overrides any key in the [http] and [prom] sections, e.g. GMON_HTTP_PORT=8080, GMON_PROM_HOST=prometheus")
+		fmt.Fprintln(fs.Output(), "\nPrecedence: flags > environment > config file > defaults")
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// 配置文件：命令行参数、环境变量、默认值
+	switch {
+	case *configFlag != "":
+		configFile = *configFlag
+	case os.Getenv(configEnv) != "":
+		configFile = os.Getenv(configEnv)
+	}
+	return fs.Args(), nil
+}
+
+// 解析 Prometheus 地址
+func parsePromUrl(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid prometheus url: %q", s)
+	}
+	var port = u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	setOverride("prom", "scheme", u.Scheme)
+	setOverride("prom", "host", u.Hostname())
+	setOverride("prom", "port", port)
+	setOverride("prom", "path", u.Path)
+	if u.User != nil {
+		passwd, _ := u.User.Password()
+		setOverride("prom", "user", u.User.Username())
+		setOverride("prom", "passwd", passwd)
+	}
+	return nil
+}
+
+func setOverride(section, key, value string) {
+	if flagOverrides[section] == nil {
+		flagOverrides[section] = make(map[string]string)
+	}
+	flagOverrides[section][key] = value
+}
+
+// 使用环境变量和命令行参数覆盖配置文件中的配置项，命令行参数优先，返回被覆盖的配置项及其来源
+func applyOverrides(file *pkg_ini.File) map[string]string {
+	var sources = make(map[string]string)
+	for _, item := range overrideKeys {
+		section := file.Section(item.section)
+		for _, key := range item.keys {
+			name := strings.ToUpper(fmt.Sprintf("gmon_%s_%s", item.section, key))
+			if value, ok := os.LookupEnv(name); ok {
+				section.Key(key).SetValue(value)
+				sources[locKey(item.section, key)] = name
+			}
+		}
+	}
+	for name, keys := range flagOverrides {
+		section := file.Section(name)
+		for key, value := range keys {
+			section.Key(key).SetValue(value)
+			sources[locKey(name, key)] = "command-line flag"
+		}
+	}
+	return sources
+}
@@ -0,0 +1,44 @@
+// @author xiangqian
+// @date 2025/09/05 21:10
+package main
+
+import (
+	pkg_ini "gopkg.in/ini.v1"
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	file, err := pkg_ini.Load([]byte("[http]\nport = 59090\nprefix = /gmon\n[prom]\nhost = localhost\nport = 9090\n[prom.dc1]\nhost = 10.0.1.10\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 命令行参数 > 环境变量 > 配置文件
+	t.Setenv("GMON_HTTP_PORT", "8080")
+	t.Setenv("GMON_PROM_HOST", "prometheus")
+	t.Setenv("GMON_PROM_PORT", "9091")
+	defer clear(flagOverrides)
+	if _, err = parseFlags([]string{"-listen", "127.0.0.1:8081", "-prom-url", "https://prom.example.com/prometheus"}); err != nil {
+		t.Fatal(err)
+	}
+	applyOverrides(file)
+
+	for _, c := range []struct {
+		section, key, value string
+	}{
+		{"http", "host", "127.0.0.1"},
+		{"http", "port", "8081"},
+		{"http", "prefix", "/gmon"},
+		{"prom", "scheme", "https"},
+		{"prom", "host", "prom.example.com"},
+		{"prom", "port", "443"},
+		{"prom", "path", "/prometheus"},
+		// 数据源小节中已配置的项不会被覆盖，未配置的项继承 [prom] 小节
+		{"prom.dc1", "host", "10.0.1.10"},
+		{"prom.dc1", "port", "443"},
+	} {
+		if value := file.Section(c.section).Key(c.key).String(); value != c.value {
+			t.Errorf("[%s] %s: %q, want %q", c.section, c.key, value, c.value)
+		}
+	}
+}
@@ -4,15 +4,21 @@ module gmon
 go 1.24
 
 require (
+	github.com/coreos/go-oidc/v3 v3.14.1
 	github.com/prometheus/client_golang v1.22.0
 	github.com/prometheus/common v0.62.0
+	golang.org/x/crypto v0.40.0
+	golang.org/x/oauth2 v0.30.0
+	golang.org/x/term v0.33.0
 	gopkg.in/ini.v1 v1.67.0
 )
 
 require (
+	github.com/go-jose/go-jose/v4 v4.0.5 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 	google.golang.org/protobuf v1.36.5 // indirect
 )
@@ -0,0 +1,126 @@
+// @author xiangqian
+// @date 2025/08/26 21:03
+package handler
+
+import (
+	"fmt"
+	"gmon/pkg/alert"
+	"gmon/pkg/prom"
+	"gmon/pkg/tmpl"
+	"gmon/pkg/user"
+	"gmon/pkg/xhttp"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 管理页面：数据源、告警规则、用户、会话和 API 令牌
+func admin(prefix string, w http.ResponseWriter, r *http.Request) {
+	renderAdmin(prefix, "", w, r)
+}
+
+// 渲染管理页面，token 为刚创建的令牌明文，只展示一次
+func renderAdmin(prefix, token string, w http.ResponseWriter, r *http.Request) {
+	var current string
+	if session, _ := xhttp.GetSession(r); session != nil {
+		current = session.Key()
+	}
+
+	sessions, err := xhttp.Sessions()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := xhttp.Tokens()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var data = map[string]any{
+		"prefix":   prefix,
+		"user":     xhttp.User(r),
+		"admin":    true,
+		"csrf":     xhttp.Csrf(r),
+		"healths":  prom.Healths(),
+		"clients":  prom.Clients(),
+		"rules":    alert.Rules(),
+		"users":    user.Users(),
+		"sessions": sessions,
+		"current":  current,
+		"tokens":   tokens,
+		"token":    token,
+		"apiToken": xhttp.TokenEnabled(),
+	}
+	tmpl.Execute(w, "admin", data)
+}
+
+// 删除会话（强制下线）
+func adminSessionDel(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.FormValue("key")
+	ok, err := xhttp.DelSessionByKey(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("session not found: %s", key), http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/admin", prefix), http.StatusFound)
+}
+
+// 创建 API 令牌
+func adminTokenCreate(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 有效期（天），0 表示永不过期
+	var expiresAt time.Time
+	days, err := strconv.Atoi(r.FormValue("days"))
+	if err != nil || days < 0 {
+		http.Error(w, fmt.Sprintf("invalid days: %s", r.FormValue("days")), http.StatusBadRequest)
+		return
+	}
+	if days > 0 {
+		expiresAt = time.Now().AddDate(0, 0, days)
+	}
+
+	token, err := xhttp.CreateToken(r.FormValue("name"), []string{r.FormValue("scope")}, expiresAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("api token created: name=%q scope=%s by=%s\n", r.FormValue("name"), r.FormValue("scope"), xhttp.User(r))
+	renderAdmin(prefix, token, w, r)
+}
+
+// 吊销 API 令牌
+func adminTokenRevoke(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.FormValue("id")
+	ok, err := xhttp.RevokeToken(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("token not found: %s", id), http.StatusNotFound)
+		return
+	}
+	log.Printf("api token revoked: id=%s by=%s\n", id, xhttp.User(r))
+	http.Redirect(w, r, fmt.Sprintf("%s/admin", prefix), http.StatusFound)
+}
@@ -0,0 +1,20 @@
+// @author xiangqian
+// @date 2025/08/09 17:02
+package handler
+
+import (
+	"gmon/pkg/alert"
+	"gmon/pkg/xjson"
+	"net/http"
+)
+
+func alerts(w http.ResponseWriter, r *http.Request) {
+	data, err := xjson.Serialize(alert.Alerts())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
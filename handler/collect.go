@@ -0,0 +1,119 @@
+// @author xiangqian
+// @date 2025/08/06 21:30
+package handler
+
+import (
+	"context"
+	"fmt"
+	"gmon/pkg/hub"
+	"gmon/pkg/notify"
+	"gmon/pkg/prom"
+	"gmon/pkg/xjson"
+	"gmon/pkg/xtime"
+	"log"
+	"time"
+)
+
+// 事件广播中心，所有 /event 连接共享同一份采集数据
+var events = hub.New()
+
+// Collect 后台定时采集数据，并广播给所有 /event 连接，直到 ctx 被取消
+func Collect(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		collect()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func collect() {
+	// 没有 /event 连接时不采集页面数据，避免无人查看时查询 Prometheus；配置了通知时仍采集实例状态
+	if events.Len() == 0 {
+		events.Clear()
+		if !notify.Enabled() {
+			return
+		}
+		apps, err := prom.Apps()
+		if err != nil {
+			log.Printf("collect: %v\n", err)
+			return
+		}
+		notifyStatus(apps, time.Now())
+		return
+	}
+
+	data, err := dat()
+	if err != nil {
+		log.Printf("collect: %v\n", err)
+		publishError(err)
+		return
+	}
+
+	if apps, ok := data["apps"].([]*prom.App); ok {
+		notifyStatus(apps, time.Now())
+	}
+
+	msg, err := xjson.Serialize(data)
+	if err != nil {
+		log.Printf("collect: %v\n", err)
+		return
+	}
+
+	events.Publish(msg)
+}
+
+// 广播采集失败，页面提示数据已过期
+func publishError(err error) {
+	msg, err := xjson.Serialize(map[string]any{"error": err.Error(), "time": xtime.XTime{Time: time.Now()}, "healths": prom.Healths()})
+	if err != nil {
+		log.Printf("collect: %v\n", err)
+		return
+	}
+
+	events.Publish(msg)
+}
+
+// 上一次采集的实例集：数据源名称,作业名称,实例地址 -> 实例
+var lastInstances map[string]*prom.Instance
+
+// 实例状态发生变化时发送通知
+func notifyStatus(apps []*prom.App, now time.Time) {
+	var instances = make(map[string]*prom.Instance)
+	for _, app := range apps {
+		for _, instance := range app.Instances {
+			var key = fmt.Sprintf("%s,%s,%s", instance.Source, instance.Name, instance.Addr)
+			instances[key] = instance
+
+			// 首次采集或者新增的实例不发送通知
+			last, ok := lastInstances[key]
+			if !ok || last.Status == instance.Status {
+				continue
+			}
+
+			// 原状态的持续时间
+			var duration time.Duration
+			if !last.Time.IsZero() {
+				duration = now.Sub(last.Time.Time)
+			}
+			notify.Send(notify.Event{
+				Type:      notify.TypeStatus,
+				Source:    instance.Source,
+				App:       app.Name,
+				Job:       instance.Name,
+				Instance:  instance.Addr,
+				OldStatus: last.Status.String(),
+				NewStatus: instance.Status.String(),
+				Timestamp: now,
+				Duration:  int64(duration.Seconds()),
+			})
+		}
+	}
+	lastInstances = instances
+}
@@ -3,11 +3,12 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"gmon/pkg/alert"
 	"gmon/pkg/prom"
-	"gmon/pkg/xjson"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -35,7 +36,28 @@ import (
 ////# Redis 总内存使用量
 ////redis_memory_used_bytes{instance="your_redis_host:port"}
 
+// 服务关闭时通知 /event 连接的客户端重连的延迟，EventSource 断开后按该延迟自动重连
+const reconnectDelay = 3 * time.Second
+
+// 服务关闭通知
+var shutdown = make(chan struct{})
+var shutdownOnce sync.Once
+
+// Shutdown 通知所有 /event 连接的客户端稍后重连，并断开连接，使服务器可以尽快完成关闭
+func Shutdown() {
+	shutdownOnce.Do(func() {
+		close(shutdown)
+	})
+}
+
 func event(w http.ResponseWriter, r *http.Request) {
+	// 事件流是长连接，不受服务器写超时限制
+	err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	header := w.Header()
 	header.Set("Content-Type", "text/event-stream")
 	header.Set("Cache-Control", "no-cache")
@@ -46,92 +68,46 @@ func event(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 创建退出通道
+	// 订阅采集数据，连接断开时取消订阅
+	ch := events.Subscribe()
+	defer events.Unsubscribe(ch)
+
 	done := r.Context().Done()
 	for {
 		select {
 		case <-done:
 			return
-		default:
-		}
-
-		data, err := dat()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		case <-shutdown:
+			fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+			flusher.Flush()
 			return
+		case data := <-ch:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
 		}
-
-		data, err = xjson.Serialize(data)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		if _, err = fmt.Fprint(w, fmt.Sprintf("data: %s\n\n", data)); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		flusher.Flush()
-
-		time.Sleep(2 * time.Second)
 	}
 }
 
-func dat() (any, error) {
+func dat() (map[string]any, error) {
 	apps, err := prom.Apps()
 	if err != nil {
 		return nil, err
 	}
 
-	var expr []string
-
-	// Prometheus
-	expr = append(expr, `label_replace(sum by (job, instance) (go_memstats_sys_bytes{job="prom"}), "name", "mem_used_bytes", "", "")`)
-
-	// Windows
-	// Windows 系统整体 CPU 使用率（0~100，单位：%）
-	expr = append(expr, `label_replace(100 - (avg by (job,instance) (rate(windows_cpu_time_total{job="windows", mode="idle"}[10s])) * 100), "name", "cpu_usage", "", "")`)
-	// Windows 系统已使用内存字节数：已使用内存 = 总物理内存 - 可用内存
-	expr = append(expr, `label_replace(windows_memory_physical_total_bytes{job="windows"} - windows_memory_physical_free_bytes{job="windows"}, "name", "mem_used_bytes", "", "")`)
-	// Windows 内存使用百分比（0~100，单位：%）
-	expr = append(expr, `label_replace(((windows_memory_physical_total_bytes{job="windows"} - windows_memory_physical_free_bytes{job="windows"}) / windows_memory_physical_total_bytes{job="windows"}) * 100, "name", "mem_used_percent", "", "")`)
-
-	//总内存和可用内存
-	//node_memory_MemTotal_bytes  # 系统总内存
-	//node_memory_MemAvailable_bytes  # 可用内存（包含缓存和缓冲）
-
-	//内存使用占比
-	//# 已用内存占比（基于 MemAvailable）
-	//1 - (node_memory_MemAvailable_bytes / node_memory_MemTotal_bytes)
-	//# 或传统计算方式（used = total - free - buffers - cache）
-	//(node_memory_MemTotal_bytes - node_memory_MemFree_bytes - node_memory_Buffers_bytes - node_memory_Cached_bytes) / node_memory_MemTotal_bytes
-
-	//总体 CPU 使用率
-	//# 1分钟平均CPU使用率（所有核心）
-	//100 - (avg by(instance) (rate(node_cpu_seconds_total{mode="idle"}[1m])) * 100
-	//# 5分钟平均CPU使用率
-	//100 - (avg by(instance) (rate(node_cpu_seconds_total{mode="idle"}[5m])) * 100
-
-	// Go
-	// go_memstats_sys_bytes：Go 总管理内存，Go 从 OS 申请的总内存（含预留）
-	// go_memstats_alloc_bytes：Go 实际使用的堆内存，当前存活对象占用的堆内存（不含空闲内存）
-	expr = append(expr, `label_replace(sum by (job, instance) (go_memstats_sys_bytes{job="go"}), "name", "mem_used_bytes", "", "")`)
-
-	// Java 已使用内存字节数
-	expr = append(expr, `label_replace(sum by (job, instance) (jvm_memory_used_bytes{job="java"}), "name", "mem_used_bytes", "", "")`)
-
-	// MySQL
-	// process_resident_memory_bytes RSS（常驻内存）
-	expr = append(expr, `label_replace(sum by (job, instance) (process_resident_memory_bytes{job="mysql"}), "name", "mem_used_bytes", "", "")`)
-
-	// Redis
-	// 查询 Redis 总内存使用量
-	expr = append(expr, `label_replace(sum by (job, instance) (redis_memory_used_bytes{job="redis"}), "name", "mem_used_bytes", "", "")`)
+	// 指标目录
+	var metrics = prom.Metrics()
+	var data = map[string]any{"apps": apps, "metrics": metrics, "alerts": alert.Alerts(), "healths": prom.Healths()}
+	if len(metrics) == 0 {
+		return data, nil
+	}
 
-	sample, err := prom.LastSample(strings.Join(expr, " or "))
+	sample, err := prom.LastSample(prom.Expr(metrics))
 	if err != nil {
 		return nil, err
 	}
+	data["sample"] = sample
 
-	return map[string]any{"apps": apps, "sample": sample}, nil
+	return data, nil
 }
@@ -4,22 +4,54 @@ package handler
 
 import (
 	"fmt"
+	"gmon/pkg/sso"
 	"gmon/pkg/xhttp"
 	"net/http"
 )
 
-func Handle(prefix, user, passwd string) {
-	xhttp.Handle(prefix, "/", func(w http.ResponseWriter, r *http.Request) {
-		index(prefix, user, w, r)
+func Handle(prefix string) {
+	xhttp.Handle(prefix, "/", xhttp.RoleViewer, func(w http.ResponseWriter, r *http.Request) {
+		index(prefix, w, r)
 	})
 	http.HandleFunc(fmt.Sprintf("%s/login", prefix), func(w http.ResponseWriter, r *http.Request) {
 		login(prefix, w, r)
 	})
 	http.HandleFunc(fmt.Sprintf("%s/login1", prefix), func(w http.ResponseWriter, r *http.Request) {
-		login1(prefix, user, passwd, w, r)
+		login1(prefix, w, r)
 	})
-	xhttp.Handle(prefix, "/logout", func(w http.ResponseWriter, r *http.Request) {
+	if sso.Enabled() {
+		http.HandleFunc(fmt.Sprintf("%s/login/oidc", prefix), func(w http.ResponseWriter, r *http.Request) {
+			loginOidc(prefix, w, r)
+		})
+		http.HandleFunc(fmt.Sprintf("%s/login/oidc/callback", prefix), func(w http.ResponseWriter, r *http.Request) {
+			loginOidcCallback(prefix, w, r)
+		})
+	}
+	http.HandleFunc(fmt.Sprintf("%s/health", prefix), health)
+	xhttp.Handle(prefix, "/logout", xhttp.RoleViewer, func(w http.ResponseWriter, r *http.Request) {
 		logout(prefix, w, r)
 	})
-	xhttp.Handle(prefix, "/event", event)
+
+	// 仪表盘
+	xhttp.Handle(prefix, "/event", xhttp.RoleViewer, event)
+	xhttp.Handle(prefix, "/history", xhttp.RoleViewer, history)
+	xhttp.Handle(prefix, "/alerts", xhttp.RoleViewer, alerts)
+	xhttp.Handle(prefix, "/instance", xhttp.RoleViewer, func(w http.ResponseWriter, r *http.Request) {
+		instance(prefix, w, r)
+	})
+	xhttp.Handle(prefix, "/instance/data", xhttp.RoleViewer, instanceData)
+
+	// 管理
+	xhttp.Handle(prefix, "/admin", xhttp.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		admin(prefix, w, r)
+	})
+	xhttp.Handle(prefix, "/admin/session/del", xhttp.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		adminSessionDel(prefix, w, r)
+	})
+	xhttp.Handle(prefix, "/admin/token/create", xhttp.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		adminTokenCreate(prefix, w, r)
+	})
+	xhttp.Handle(prefix, "/admin/token/revoke", xhttp.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		adminTokenRevoke(prefix, w, r)
+	})
 }
@@ -0,0 +1,39 @@
+// @author xiangqian
+// @date 2025/08/24 11:05
+package handler
+
+import (
+	"gmon/pkg/prom"
+	"gmon/pkg/xjson"
+	"net/http"
+)
+
+// 数据源健康状态（名称、是否可用、当前状态的开始时间），无需登录，便于负载均衡和监控系统探测。
+// gmon 自身可用时始终返回 200，数据源全部不可用时 status 为 down，部分不可用时为 degraded
+func health(w http.ResponseWriter, r *http.Request) {
+	var healths = prom.Healths()
+	var up = 0
+	for i, health := range healths {
+		if health.Up {
+			up++
+		}
+		// 无需登录，不返回错误详情（可能包含数据源的内部地址），错误详情只在登录后的页面中展示
+		healths[i].Error = ""
+	}
+
+	var status = "ok"
+	if up == 0 && len(healths) > 0 {
+		status = "down"
+	} else if up < len(healths) {
+		status = "degraded"
+	}
+
+	data, err := xjson.Serialize(map[string]any{"status": status, "sources": healths})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
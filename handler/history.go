@@ -0,0 +1,65 @@
+// @author xiangqian
+// @date 2025/08/04 20:13
+package handler
+
+import (
+	"fmt"
+	"gmon/pkg/prom"
+	"gmon/pkg/xjson"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 历史采样默认时长（分钟）
+const defaultHistoryMinutes = 15
+
+// 历史采样最大时长（分钟）
+const maxHistoryMinutes = 60
+
+// 历史采样最小步长，与事件推送间隔一致
+const minHistoryStep = 2 * time.Second
+
+// 历史采样最大数据点数，与首页图表保留的数据点数一致
+const maxHistoryPoints = 500
+
+func history(w http.ResponseWriter, r *http.Request) {
+	// 时长（分钟）
+	var minutes = defaultHistoryMinutes
+	if s := r.URL.Query().Get("minutes"); s != "" {
+		var err error
+		minutes, err = strconv.Atoi(s)
+		if err != nil || minutes <= 0 || minutes > maxHistoryMinutes {
+			http.Error(w, fmt.Sprintf("minutes must be between 1 and %d", maxHistoryMinutes), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// 步长
+	var duration = time.Duration(minutes) * time.Minute
+	var step = (duration / maxHistoryPoints).Round(time.Second)
+	if step < minHistoryStep {
+		step = minHistoryStep
+	}
+
+	var data = map[string]any{}
+	var metrics = prom.Metrics()
+	data["metrics"] = metrics
+	if len(metrics) > 0 {
+		samples, err := prom.LastRangeSample(prom.Expr(metrics), duration, step)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["samples"] = samples
+	}
+
+	buf, err := xjson.Serialize(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
@@ -4,22 +4,29 @@ package handler
 
 import (
 	"fmt"
+	"gmon/pkg/alert"
 	"gmon/pkg/prom"
 	"gmon/pkg/tmpl"
+	"gmon/pkg/xhttp"
 	"net/http"
 )
 
-func index(prefix, user string, w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path == "" || r.URL.Path == fmt.Sprintf("/%s", prefix) {
+func index(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "" || r.URL.Path == fmt.Sprintf("%s/", prefix) {
 		var data = make(map[string]any)
 		data["prefix"] = prefix
-		data["user"] = user
+		data["user"] = xhttp.User(r)
+		data["admin"] = xhttp.GetRole(r) >= xhttp.RoleAdmin
+		data["csrf"] = xhttp.Csrf(r)
 
 		apps, err := prom.Apps()
 		if err != nil {
 			data["error"] = err.Error()
 		}
 		data["apps"] = apps
+		data["sources"] = prom.GroupBySource(apps)
+		data["alerts"] = alert.Alerts()
+		data["healths"] = prom.Healths()
 
 		tmpl.Execute(w, "index", data)
 		return
@@ -0,0 +1,131 @@
+// @author xiangqian
+// @date 2025/08/03 09:42
+package handler
+
+import (
+	"fmt"
+	"gmon/pkg/prom"
+	"gmon/pkg/tmpl"
+	"gmon/pkg/xhttp"
+	"gmon/pkg/xjson"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 时间范围集
+var ranges = []Range{
+	{Name: "1h", Duration: time.Hour, Step: 15 * time.Second},
+	{Name: "6h", Duration: 6 * time.Hour, Step: time.Minute},
+	{Name: "24h", Duration: 24 * time.Hour, Step: 5 * time.Minute},
+	{Name: "7d", Duration: 7 * 24 * time.Hour, Step: 30 * time.Minute},
+}
+
+// 步长集
+var steps = []string{"15s", "1m", "5m", "15m", "30m", "1h"}
+
+// Prometheus 区间查询单个系列最大的数据点数
+const maxPoints = 11000
+
+func instance(prefix string, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var data = map[string]any{
+		"prefix":   prefix,
+		"user":     xhttp.User(r),
+		"admin":    xhttp.GetRole(r) >= xhttp.RoleAdmin,
+		"csrf":     xhttp.Csrf(r),
+		"source":   query.Get("source"),
+		"job":      query.Get("job"),
+		"instance": query.Get("instance"),
+		"ranges":   ranges,
+		"steps":    steps,
+		"healths":  prom.Healths(),
+	}
+	tmpl.Execute(w, "instance", data)
+}
+
+func instanceData(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	job := strings.TrimSpace(query.Get("job"))
+	inst := strings.TrimSpace(query.Get("instance"))
+	if job == "" || inst == "" {
+		http.Error(w, "job and instance are required", http.StatusBadRequest)
+		return
+	}
+
+	// 数据源
+	client := prom.GetClient(query.Get("source"))
+	if client == nil {
+		http.Error(w, fmt.Sprintf("invalid source: %s", query.Get("source")), http.StatusBadRequest)
+		return
+	}
+
+	// 时间范围
+	var rng *Range
+	for i := range ranges {
+		if ranges[i].Name == query.Get("range") {
+			rng = &ranges[i]
+			break
+		}
+	}
+	if rng == nil {
+		http.Error(w, fmt.Sprintf("invalid range: %s", query.Get("range")), http.StatusBadRequest)
+		return
+	}
+
+	// 步长
+	var step = rng.Step
+	if s := query.Get("step"); s != "" {
+		var err error
+		step, err = time.ParseDuration(s)
+		if err != nil || step <= 0 {
+			http.Error(w, fmt.Sprintf("invalid step: %s", s), http.StatusBadRequest)
+			return
+		}
+	}
+	if int64(rng.Duration/step) > maxPoints {
+		http.Error(w, fmt.Sprintf("step %s is too small for range %s", step, rng.Name), http.StatusBadRequest)
+		return
+	}
+
+	// 实例所属作业的指标，以及在线状态
+	var metrics []prom.Metric
+	for _, metric := range prom.Metrics() {
+		if metric.Job == job {
+			metrics = append(metrics, metric)
+		}
+	}
+	metrics = append(metrics, prom.Metric{
+		Job:   job,
+		Name:  "up",
+		Expr:  "up",
+		Label: "UP",
+		Axis:  "left",
+		Chart: "UP",
+	})
+
+	// 只保留该实例的系列
+	expr := fmt.Sprintf(`(%s) and on (job, instance) up{job=%q, instance=%q}`, prom.Expr(metrics), job, inst)
+	end := time.Now()
+	samples, err := client.RangeSample(expr, end.Add(-rng.Duration), end, step)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := xjson.Serialize(map[string]any{"metrics": metrics, "samples": samples})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// Range 时间范围
+type Range struct {
+	Name     string        // 名称
+	Duration time.Duration // 时长
+	Step     time.Duration // 默认步长
+}
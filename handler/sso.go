@@ -0,0 +1,75 @@
+// @author xiangqian
+// @date 2025/08/30 16:20
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"gmon/pkg/sso"
+	"gmon/pkg/xhttp"
+	"golang.org/x/oauth2"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// 单点登录状态 Cookie 的有效期（单位：秒）
+const ssoMaxAge = 10 * 60
+
+// 重定向到身份提供方
+func loginOidc(prefix string, w http.ResponseWriter, r *http.Request) {
+	// state 防止 CSRF，nonce 防止 ID Token 重放，verifier 为 PKCE 校验码
+	state, nonce, verifier := rand.Text(), rand.Text(), oauth2.GenerateVerifier()
+	url, err := sso.AuthCodeUrl(state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc login: %v\n", err)
+		ssoError(prefix, w, r, "单点登录不可用")
+		return
+	}
+
+	xhttp.SetCookie(w, "oidc", strings.Join([]string{state, nonce, verifier}, ","), ssoMaxAge)
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// 身份提供方回调
+func loginOidcCallback(prefix string, w http.ResponseWriter, r *http.Request) {
+	value, err := xhttp.GetCookie(r, "oidc")
+	xhttp.SetCookie(w, "oidc", "", -1)
+	arr := strings.Split(value, ",")
+	if err != nil || len(arr) != 3 {
+		ssoError(prefix, w, r, "页面已过期，请重新登录")
+		return
+	}
+	state, nonce, verifier := arr[0], arr[1], arr[2]
+
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+		ssoError(prefix, w, r, "页面已过期，请重新登录")
+		return
+	}
+	if e := query.Get("error"); e != "" {
+		log.Printf("oidc login failed: ip=%s error=%s %s\n", xhttp.ClientIp(r), e, query.Get("error_description"))
+		ssoError(prefix, w, r, fmt.Sprintf("单点登录失败：%s", e))
+		return
+	}
+
+	identity, err := sso.Exchange(r.Context(), query.Get("code"), nonce, verifier)
+	if err != nil {
+		log.Printf("oidc login failed: ip=%s %v\n", xhttp.ClientIp(r), err)
+		ssoError(prefix, w, r, "单点登录失败")
+		return
+	}
+
+	err = xhttp.SetSession(w, r, identity.User, identity.Role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/", prefix), http.StatusFound)
+}
+
+func ssoError(prefix string, w http.ResponseWriter, r *http.Request, msg string) {
+	xhttp.SetCookie(w, "error", msg, 2)
+	http.Redirect(w, r, fmt.Sprintf("%s/login", prefix), http.StatusFound)
+}
@@ -4,31 +4,50 @@ package handler
 
 import (
 	"fmt"
+	"gmon/pkg/sso"
 	"gmon/pkg/tmpl"
+	"gmon/pkg/user"
 	"gmon/pkg/xhttp"
+	"log"
 	"net/http"
+	"time"
 )
 
 func login(prefix string, w http.ResponseWriter, r *http.Request) {
-	// 会话是否已过期
-	if !xhttp.Expired(r) {
+	// 会话是否已过期，或者已由受信任的反向代理认证
+	if !xhttp.Expired(r) || xhttp.ProxyUser(r) != "" {
 		// 没有过期则重定向到首页
 		http.Redirect(w, r, fmt.Sprintf("%s/", prefix), http.StatusFound)
 		return
 	}
 
+	// CSRF 令牌
+	csrf, err := xhttp.LoginCsrf(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	user, _ := xhttp.GetCookie(r, "user")
-	err, _ := xhttp.GetCookie(r, "error")
+	e, _ := xhttp.GetCookie(r, "error")
 	var data = map[string]any{
-		"prefix": prefix,
-		"user":   user,
-		"error":  err,
+		"prefix":  prefix,
+		"user":    user,
+		"error":   e,
+		"csrf":    csrf,
+		"sso":     sso.Enabled(),
+		"ssoName": sso.Name(),
 	}
 	tmpl.Execute(w, "login", data)
 	return
 }
 
-func login1(prefix, user, passwd string, w http.ResponseWriter, r *http.Request) {
+func login1(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
 	// 解析表单数据
 	err := r.ParseForm()
 	if err != nil {
@@ -37,20 +56,52 @@ func login1(prefix, user, passwd string, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// 校验 CSRF 令牌
+	if !xhttp.CheckLoginCsrf(w, r) {
+		xhttp.SetCookie(w, "error", "页面已过期，请重新登录", 2)
+		http.Redirect(w, r, fmt.Sprintf("%s/login", prefix), http.StatusFound)
+		return
+	}
+
 	ruser := r.FormValue("user")
 	rpasswd := r.FormValue("passwd")
-	if ruser == user && rpasswd == passwd {
-		xhttp.SetSession(w)
+
+	// 登录失败次数过多，拒绝登录
+	ip := xhttp.ClientIp(r)
+	if wait := user.Wait(ip, ruser); wait > 0 {
+		log.Printf("login rejected: user=%q ip=%s retry_after=%s\n", ruser, ip, wait.Round(time.Second))
+		xhttp.SetCookie(w, "user", ruser, 2)
+		xhttp.SetCookie(w, "error", fmt.Sprintf("登录失败次数过多，请 %s 后重试", wait.Round(time.Second)), 2)
+		http.Redirect(w, r, fmt.Sprintf("%s/login", prefix), http.StatusFound)
+		return
+	}
+
+	if u, ok := user.Authenticate(ruser, rpasswd); ok {
+		user.Succeeded(ip, ruser)
+		err = xhttp.SetSession(w, r, u.Name, u.Role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		http.Redirect(w, r, fmt.Sprintf("%s/", prefix), http.StatusFound)
 		return
 	}
 
+	// 审计日志
+	count, wait := user.Failed(ip, ruser)
+	log.Printf("login failed: user=%q ip=%s failures=%d retry_after=%s\n", ruser, ip, count, wait)
+
 	xhttp.SetCookie(w, "user", ruser, 2)
 	xhttp.SetCookie(w, "error", "用户名或密码错误", 2)
 	http.Redirect(w, r, fmt.Sprintf("%s/login", prefix), http.StatusFound)
 }
 
+// 登出，只接受 POST 请求（CSRF 令牌由 xhttp.Handle 校验）
 func logout(prefix string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
 	xhttp.DelSession(w, r)
 	http.Redirect(w, r, fmt.Sprintf("%s/login", prefix), http.StatusFound)
 }
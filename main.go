@@ -3,32 +3,112 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"gmon/handler"
+	"gmon/pkg/alert"
+	"gmon/pkg/notify"
 	"gmon/pkg/prom"
+	"gmon/pkg/sso"
 	"gmon/pkg/static"
 	"gmon/pkg/tmpl"
+	"gmon/pkg/user"
+	"gmon/pkg/xhttp"
 	"gmon/pkg/xlog"
 	"log"
-	"net/http"
+	"os"
+	"time"
 )
 
 func main() {
+	// 命令行参数
+	args, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	// 子命令
+	if len(args) > 0 {
+		switch args[0] {
+		case "add-user":
+			if err := addUser(args[1:]); err != nil {
+				log.Fatalf("add-user: %v\n", err)
+			}
+			return
+		case "check-config":
+			if err := checkConfig(args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		default:
+			log.Fatalf("unknown command: %s\n", args[0])
+		}
+	}
+
 	// [config]
-	config, err := LoadConfig()
+	config, err := LoadConfig(configFile)
+	if err == nil {
+		err = config.Validate()
+	}
 	if err != nil {
-		log.Fatalf("load config: %v\n", err)
+		log.Fatalf("load config:\n%v\n", err)
 	}
 
 	// [log]
 	xlog.Init()
 
 	// [prom]
-	err = prom.Init(config.Prom)
+	err = prom.Init(config.Prom, config.Metrics)
 	if err != nil {
 		log.Fatalf("init prom: %v\n", err)
 	}
 
+	// [alert]
+	err = alert.Init(config.Alert)
+	if err != nil {
+		log.Fatalf("init alert: %v\n", err)
+	}
+
+	// [notify]
+	err = notify.Init(config.Notify)
+	if err != nil {
+		log.Fatalf("init notify: %v\n", err)
+	}
+
+	// [user]
+	err = user.Init(config.Http.Users, config.Http.Roles)
+	if err != nil {
+		log.Fatalf("init user: %v\n", err)
+	}
+	user.InitLimit(config.Http.Limit)
+
+	// [sso]
+	err = sso.Init(config.Http.Oidc)
+	if err != nil {
+		log.Fatalf("init sso: %v\n", err)
+	}
+
+	// [session]
+	err = xhttp.Init(config.Session)
+	if err != nil {
+		log.Fatalf("init session: %v\n", err)
+	}
+
+	// [proxy]
+	xhttp.InitProxy(config.Http.Proxy)
+
+	// [api]
+	err = xhttp.InitTokens(config.Http.Tokens)
+	if err != nil {
+		log.Fatalf("init api token: %v\n", err)
+	}
+
 	// [static]
 	err = static.Init(config.Http.Prefix)
 	if err != nil {
@@ -42,13 +122,12 @@ func main() {
 	}
 
 	// [handler]
-	handler.Handle(config.Http.Prefix, config.Http.User, config.Http.Passwd)
+	handler.Handle(config.Http.Prefix)
 
-	// 启动服务器
-	var port = config.Http.Port
-	log.Printf("Server starting on port %d ...\n", port)
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-	if err != nil {
-		log.Fatalf("ListenAndServe: %v\n", err)
-	}
+	// 启动服务器和后台任务，直到收到 SIGINT、SIGTERM 信号
+	serve(config.Http, prom.Run, func(ctx context.Context) {
+		handler.Collect(ctx, 2*time.Second)
+	}, alert.Run, notify.Run, func(ctx context.Context) {
+		watch(ctx, configFile, config)
+	})
 }
@@ -0,0 +1,322 @@
+// @author xiangqian
+// @date 2025/08/09 15:20
+package alert
+
+import (
+	"context"
+	"fmt"
+	"gmon/pkg/notify"
+	"gmon/pkg/prom"
+	"gmon/pkg/xtime"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 告警规则
+var rules []Rule
+
+// 告警评估间隔
+var interval time.Duration
+
+// 已恢复告警的保留时长
+const resolvedRetention = time.Hour
+
+// 读写互斥锁
+var rwMutex sync.RWMutex
+
+// 告警集：规则名称,数据源名称,作业名称,实例地址 -> 告警
+var alerts = make(map[string]*Alert)
+
+// Init 初始化告警规则，重新加载配置时再次调用，移除已删除规则的告警
+func Init(config Config) error {
+	err := Check(config)
+	if err != nil {
+		return err
+	}
+
+	rwMutex.Lock()
+	defer rwMutex.Unlock()
+
+	rules = config.Rules
+	interval = config.Interval
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	var names = make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		names[rule.Name] = true
+	}
+	for key, alert := range alerts {
+		if !names[alert.Rule] {
+			delete(alerts, key)
+		}
+	}
+	return nil
+}
+
+// Check 校验告警规则
+func Check(config Config) error {
+	for _, rule := range config.Rules {
+		switch rule.Type {
+		case TypeDown:
+		case TypeThreshold:
+			if _, ok := ops[rule.Op]; !ok {
+				return fmt.Errorf("alert %s: invalid op %q", rule.Name, rule.Op)
+			}
+			if rule.Metric == "" {
+				return fmt.Errorf("alert %s: metric is required", rule.Name)
+			}
+		default:
+			return fmt.Errorf("alert %s: invalid type %q", rule.Name, rule.Type)
+		}
+	}
+	return nil
+}
+
+// Run 定时评估告警规则，直到 ctx 被取消；重新加载配置后使用新的规则和评估间隔
+func Run(ctx context.Context) {
+	var d = getInterval()
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		for _, rule := range Rules() {
+			// 分别评估每个数据源，数据源不可用时只记录日志，避免该数据源上的告警被误判为已恢复
+			for _, client := range prom.Clients() {
+				if !client.Healthy() {
+					continue
+				}
+				matches, err := rule.Eval(client)
+				if err != nil {
+					log.Printf("alert %s %s: %v\n", rule.Name, client.Name(), err)
+					continue
+				}
+				update(rule, client.Name(), matches, time.Now())
+			}
+		}
+
+		if i := getInterval(); i != d {
+			d = i
+			ticker.Reset(d)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rules 告警规则
+func Rules() []Rule {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	return rules
+}
+
+// 告警评估间隔
+func getInterval() time.Duration {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	if interval <= 0 {
+		return 15 * time.Second
+	}
+	return interval
+}
+
+// Alerts 告警集（待触发、已触发以及最近已恢复的告警），按状态和开始时间排序
+func Alerts() []*Alert {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	var arr = make([]*Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		// 复制一份，避免并发读写
+		var a = *alert
+		arr = append(arr, &a)
+	}
+	sort.Slice(arr, func(i, j int) bool {
+		if arr[i].State != arr[j].State {
+			return arr[i].State > arr[j].State
+		}
+		return arr[i].ActiveAt.Before(arr[j].ActiveAt.Time)
+	})
+	return arr
+}
+
+// 根据数据源的评估结果更新告警状态
+func update(rule Rule, source string, matches []Match, now time.Time) {
+	rwMutex.Lock()
+	defer rwMutex.Unlock()
+
+	var matched = make(map[string]bool, len(matches))
+	for _, match := range matches {
+		var key = fmt.Sprintf("%s,%s,%s,%s", rule.Name, source, match.Job, match.Instance)
+		matched[key] = true
+
+		alert, ok := alerts[key]
+		if !ok || alert.State == StateResolved {
+			// 新告警
+			alert = &Alert{
+				Rule:     rule.Name,
+				Source:   source,
+				Job:      match.Job,
+				Instance: match.Instance,
+				State:    StatePending,
+				ActiveAt: xtime.XTime{Time: now},
+			}
+			alerts[key] = alert
+		}
+		alert.Value = match.Value
+		if alert.State == StatePending && now.Sub(alert.ActiveAt.Time) >= rule.For {
+			// 持续时间达到阈值，触发告警
+			alert.State = StateFiring
+			alert.FiredAt = xtime.XTime{Time: now}
+			notifyState(alert, StatePending, alert.ActiveAt.Time, now)
+		}
+	}
+
+	for key, alert := range alerts {
+		if alert.Rule != rule.Name || alert.Source != source || matched[key] {
+			continue
+		}
+
+		switch alert.State {
+		case StatePending:
+			// 未触发即恢复，直接移除
+			delete(alerts, key)
+		case StateFiring:
+			alert.State = StateResolved
+			alert.ResolvedAt = xtime.XTime{Time: now}
+			notifyState(alert, StateFiring, alert.FiredAt.Time, now)
+		case StateResolved:
+			if now.Sub(alert.ResolvedAt.Time) > resolvedRetention {
+				delete(alerts, key)
+			}
+		}
+	}
+}
+
+// 告警状态发生变化时发送通知
+func notifyState(alert *Alert, old State, since, now time.Time) {
+	notify.Send(notify.Event{
+		Type:      notify.TypeAlert,
+		Source:    alert.Source,
+		Job:       alert.Job,
+		Instance:  alert.Instance,
+		Rule:      alert.Rule,
+		OldStatus: old.String(),
+		NewStatus: alert.State.String(),
+		Timestamp: now,
+		Duration:  int64(now.Sub(since).Seconds()),
+	})
+}
+
+// 比较运算符
+var ops = map[string]bool{">": true, ">=": true, "<": true, "<=": true, "==": true, "!=": true}
+
+// Eval 在数据源上评估告警规则，返回满足条件的实例
+func (rule Rule) Eval(client *prom.Client) ([]Match, error) {
+	var expr string
+	switch rule.Type {
+	case TypeDown:
+		expr = "up == 0"
+	case TypeThreshold:
+		var metrics []prom.Metric
+		for _, metric := range prom.Metrics() {
+			if metric.Name == rule.Metric {
+				metrics = append(metrics, metric)
+			}
+		}
+		if len(metrics) == 0 {
+			return nil, nil
+		}
+		expr = fmt.Sprintf("(%s) %s %g", prom.Expr(metrics), rule.Op, rule.Threshold)
+	}
+
+	vector, err := client.Vector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches = make([]Match, 0, len(vector))
+	for _, samp := range vector {
+		matches = append(matches, Match{
+			Job:      string(samp.Metric["job"]),
+			Instance: string(samp.Metric["instance"]),
+			Value:    float64(samp.Value),
+		})
+	}
+	return matches, nil
+}
+
+// Config 告警配置
+type Config struct {
+	Interval time.Duration // 评估间隔
+	Rules    []Rule        // 告警规则
+}
+
+// Rule 告警规则
+type Rule struct {
+	Name      string        // 规则名称
+	Type      string        // 规则类型：down（实例离线）、threshold（指标阈值）
+	Metric    string        // 指标系列名称（threshold），如：cpu_usage、mem_used_percent
+	Op        string        // 比较运算符（threshold）：>、>=、<、<=、==、!=
+	Threshold float64       // 阈值（threshold）
+	For       time.Duration // 条件持续满足多长时间后触发告警
+}
+
+const (
+	TypeDown      = "down"      // 实例离线
+	TypeThreshold = "threshold" // 指标阈值
+)
+
+// Match 满足告警条件的实例
+type Match struct {
+	Job      string  // 作业名称
+	Instance string  // 实例地址
+	Value    float64 // 当前值
+}
+
+// Alert 告警
+type Alert struct {
+	Rule       string      `json:"rule"`       // 规则名称
+	Source     string      `json:"source"`     // 数据源名称
+	Job        string      `json:"job"`        // 作业名称
+	Instance   string      `json:"instance"`   // 实例地址
+	State      State       `json:"state"`      // 状态
+	Value      float64     `json:"value"`      // 最近一次评估的值
+	ActiveAt   xtime.XTime `json:"activeAt"`   // 开始满足条件的时间
+	FiredAt    xtime.XTime `json:"firedAt"`    // 触发时间
+	ResolvedAt xtime.XTime `json:"resolvedAt"` // 恢复时间
+}
+
+type State byte
+
+const (
+	StateResolved State = iota + 1
+	StatePending
+	StateFiring
+)
+
+func (state State) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + state.String() + `"`), nil
+}
+
+func (state State) String() string {
+	switch state {
+	case StatePending:
+		return "PENDING"
+	case StateFiring:
+		return "FIRING"
+	case StateResolved:
+		return "RESOLVED"
+	default:
+		return "UNKNOWN"
+	}
+}
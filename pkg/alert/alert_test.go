@@ -0,0 +1,58 @@
+// @author xiangqian
+// @date 2025/08/09 17:40
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdate(t *testing.T) {
+	var rule = Rule{Name: "cpu_high", Type: TypeThreshold, Metric: "cpu_usage", Op: ">", Threshold: 90, For: time.Minute}
+	var match = Match{Job: "linux", Instance: "h1:9100", Value: 95}
+	var now = time.Now()
+	var state = func() State {
+		arr := Alerts()
+		if len(arr) == 0 {
+			return 0
+		}
+		return arr[0].State
+	}
+
+	// 开始满足条件：待触发
+	update(rule, "default", []Match{match}, now)
+	if s := state(); s != StatePending {
+		t.Fatalf("want PENDING, got %s", s)
+	}
+
+	// 持续时间未达到阈值：仍待触发
+	update(rule, "default", []Match{match}, now.Add(30*time.Second))
+	if s := state(); s != StatePending {
+		t.Fatalf("want PENDING, got %s", s)
+	}
+
+	// 持续时间达到阈值：已触发
+	update(rule, "default", []Match{match}, now.Add(time.Minute))
+	if s := state(); s != StateFiring {
+		t.Fatalf("want FIRING, got %s", s)
+	}
+
+	// 不再满足条件：已恢复
+	update(rule, "default", nil, now.Add(2*time.Minute))
+	if s := state(); s != StateResolved {
+		t.Fatalf("want RESOLVED, got %s", s)
+	}
+
+	// 超过保留时长：移除
+	update(rule, "default", nil, now.Add(2*time.Minute+resolvedRetention+time.Second))
+	if s := state(); s != 0 {
+		t.Fatalf("want removed, got %s", s)
+	}
+
+	// 未触发即恢复：直接移除
+	update(rule, "default", []Match{match}, now)
+	update(rule, "default", nil, now.Add(time.Second))
+	if s := state(); s != 0 {
+		t.Fatalf("want removed, got %s", s)
+	}
+}
@@ -0,0 +1,77 @@
+// @author xiangqian
+// @date 2025/08/06 21:08
+package hub
+
+import (
+	"sync"
+)
+
+// Hub 广播中心，将发布的消息分发给所有订阅者
+type Hub struct {
+	mutex       sync.Mutex           // 互斥锁
+	subscribers map[chan []byte]bool // 订阅者集
+	last        []byte               // 最近一次发布的消息
+}
+
+// New 创建广播中心
+func New() *Hub {
+	return &Hub{subscribers: make(map[chan []byte]bool)}
+}
+
+// Subscribe 订阅，如果已有发布的消息，则立即收到最近一次发布的消息
+func (hub *Hub) Subscribe() chan []byte {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	// 缓冲区大小为 1，只保留最新的消息
+	ch := make(chan []byte, 1)
+	if hub.last != nil {
+		ch <- hub.last
+	}
+	hub.subscribers[ch] = true
+	return ch
+}
+
+// Unsubscribe 取消订阅
+func (hub *Hub) Unsubscribe(ch chan []byte) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	delete(hub.subscribers, ch)
+}
+
+// Publish 发布消息，不会因为订阅者消费慢而阻塞，订阅者来不及消费的旧消息会被丢弃
+func (hub *Hub) Publish(msg []byte) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.last = msg
+	for ch := range hub.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			// 丢弃旧消息，替换为新消息
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- msg
+		}
+	}
+}
+
+// Clear 清除最近一次发布的消息，新订阅者不会收到过期的消息
+func (hub *Hub) Clear() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.last = nil
+}
+
+// Len 订阅者数量
+func (hub *Hub) Len() int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	return len(hub.subscribers)
+}
@@ -0,0 +1,48 @@
+// @author xiangqian
+// @date 2025/08/06 22:01
+package hub
+
+import (
+	"testing"
+)
+
+func TestHub(t *testing.T) {
+	hub := New()
+	ch1 := hub.Subscribe()
+
+	// 订阅者来不及消费时，只保留最新的消息
+	hub.Publish([]byte("1"))
+	hub.Publish([]byte("2"))
+	if msg := string(<-ch1); msg != "2" {
+		t.Fatalf("ch1: %s", msg)
+	}
+
+	// 新订阅者立即收到最近一次发布的消息
+	ch2 := hub.Subscribe()
+	if msg := string(<-ch2); msg != "2" {
+		t.Fatalf("ch2: %s", msg)
+	}
+
+	hub.Unsubscribe(ch1)
+	hub.Publish([]byte("3"))
+	select {
+	case msg := <-ch1:
+		t.Fatalf("unsubscribed ch1: %s", msg)
+	default:
+	}
+	if msg := string(<-ch2); msg != "3" {
+		t.Fatalf("ch2: %s", msg)
+	}
+	if hub.Len() != 1 {
+		t.Fatalf("len: %d", hub.Len())
+	}
+
+	// 清除后新订阅者不会收到过期的消息
+	hub.Clear()
+	ch3 := hub.Subscribe()
+	select {
+	case msg := <-ch3:
+		t.Fatalf("cleared ch3: %s", msg)
+	default:
+	}
+}
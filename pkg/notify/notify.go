@@ -0,0 +1,146 @@
+// @author xiangqian
+// @date 2025/08/12 20:16
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 通知器集
+var notifiers []Notifier
+
+// 读写互斥锁，保护通知器集，重新加载配置时替换
+var rwMutex sync.RWMutex
+
+// 待发送的事件队列
+var queue = make(chan Event, 100)
+
+// Init 初始化通知器，重新加载配置时再次调用，替换通知器集
+func Init(config Config) error {
+	err := Check(config)
+	if err != nil {
+		return err
+	}
+
+	var arr []Notifier
+	if len(config.Webhook.Urls) > 0 {
+		arr = append(arr, NewWebhook(config.Webhook))
+	}
+	if config.Smtp.Host != "" {
+		arr = append(arr, NewSmtp(config.Smtp))
+	}
+
+	rwMutex.Lock()
+	var old = notifiers
+	notifiers = arr
+	rwMutex.Unlock()
+
+	// 关闭被替换的通知器，不再使用原配置（收件人、认证等）发送
+	for _, notifier := range old {
+		notifier.Close()
+	}
+	return nil
+}
+
+// Check 校验通知配置
+func Check(config Config) error {
+	if config.Smtp.Host != "" {
+		switch config.Smtp.Tls {
+		case TlsNone, TlsImplicit, TlsStartTls:
+		default:
+			return fmt.Errorf("smtp: invalid tls %q", config.Smtp.Tls)
+		}
+		if config.Smtp.From == "" || len(config.Smtp.To) == 0 {
+			return fmt.Errorf("smtp: from and to are required")
+		}
+	}
+	return nil
+}
+
+// 通知器集
+func getNotifiers() []Notifier {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	return notifiers
+}
+
+// Enabled 是否配置了通知器
+func Enabled() bool {
+	return len(getNotifiers()) > 0
+}
+
+// Send 发送事件，事件进入队列后异步发送，队列已满时丢弃事件
+func Send(event Event) {
+	if !Enabled() {
+		return
+	}
+
+	select {
+	case queue <- event:
+	default:
+		log.Printf("notify: queue is full, drop event %s %s %s\n", event.Type, event.Job, event.Instance)
+	}
+}
+
+// Run 从队列中取出事件并发送给所有通知器，直到 ctx 被取消
+func Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			for _, notifier := range getNotifiers() {
+				notifier.Close()
+			}
+			return
+		case event := <-queue:
+			for _, notifier := range getNotifiers() {
+				if err := notifier.Notify(ctx, event); err != nil {
+					log.Printf("notify: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// Notifier 通知器
+type Notifier interface {
+	// Notify 发送事件
+	Notify(ctx context.Context, event Event) error
+
+	// Close 关闭通知器，通知器被替换或者服务关闭时调用
+	Close()
+}
+
+// Event 通知事件
+type Event struct {
+	Type      string    `json:"type"`           // 类型：status（实例状态变化）、alert（告警状态变化）
+	Source    string    `json:"source"`         // 数据源名称
+	App       string    `json:"app"`            // 应用名称
+	Job       string    `json:"job"`            // 作业名称
+	Instance  string    `json:"instance"`       // 实例地址
+	Rule      string    `json:"rule,omitempty"` // 告警规则名称（alert）
+	OldStatus string    `json:"oldStatus"`      // 原状态
+	NewStatus string    `json:"newStatus"`      // 新状态
+	Timestamp time.Time `json:"timestamp"`      // 状态变化时间
+	Duration  int64     `json:"duration"`       // 原状态的持续时间（单位：秒）
+}
+
+// Resolved 是否为恢复通知：实例上线、告警恢复
+func (event Event) Resolved() bool {
+	return event.NewStatus == "UP" || event.NewStatus == "RESOLVED"
+}
+
+const (
+	TypeStatus = "status" // 实例状态变化
+	TypeAlert  = "alert"  // 告警状态变化
+)
+
+// Config 通知配置
+type Config struct {
+	Webhook WebhookConfig // Webhook 配置
+	Smtp    SmtpConfig    // 邮件配置
+}
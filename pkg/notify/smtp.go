@@ -0,0 +1,261 @@
+// @author xiangqian
+// @date 2025/08/15 19:52
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"gmon/pkg/tmpl"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Smtp 邮件通知器，同一实例在限制间隔内只发送一封邮件：
+// 恢复通知（实例上线、告警恢复）在最近一次发送的是故障通知时立即发送，避免运维人员看到的一直是故障状态；
+// 其他因频率限制未发送的通知，在限制间隔结束时发送其中最新的一条，并附带未发送的通知数
+type Smtp struct {
+	config SmtpConfig
+
+	mutex  sync.Mutex            // 互斥锁
+	states map[string]*smtpState // 数据源名称,作业名称,实例地址 -> 发送状态
+
+	// 限制间隔结束时的发送使用的上下文，通知器关闭时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// 实例的邮件发送状态
+type smtpState struct {
+	sentAt     time.Time   // 最近一次发送时间
+	resolved   bool        // 最近一次发送的是否为恢复通知
+	pending    *Event      // 因频率限制未发送的最新通知
+	suppressed int         // 因频率限制未发送的通知数
+	timer      *time.Timer // 限制间隔结束时发送未发送的通知
+}
+
+// NewSmtp 创建邮件通知器
+func NewSmtp(config SmtpConfig) *Smtp {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Smtp{
+		config: config,
+		states: make(map[string]*smtpState),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (s *Smtp) Notify(ctx context.Context, event Event) error {
+	// 频率限制
+	suppressed, ok := s.allow(event)
+	if !ok {
+		return nil
+	}
+	return s.mail(ctx, event, suppressed)
+}
+
+// 是否允许发送，返回此前因频率限制未发送的通知数
+func (s *Smtp) allow(event Event) (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var key = fmt.Sprintf("%s,%s,%s", event.Source, event.Job, event.Instance)
+	state, ok := s.states[key]
+	if !ok {
+		state = &smtpState{}
+		s.states[key] = state
+	}
+
+	var elapsed = event.Timestamp.Sub(state.sentAt)
+	if !state.sentAt.IsZero() && elapsed < s.config.Interval && !(event.Resolved() && !state.resolved) {
+		state.pending = &event
+		state.suppressed++
+		if state.timer == nil && s.ctx.Err() == nil {
+			state.timer = time.AfterFunc(s.config.Interval-elapsed, func() {
+				s.flush(key)
+			})
+		}
+		return 0, false
+	}
+
+	var suppressed = state.suppressed
+	state.sentAt = event.Timestamp
+	state.resolved = event.Resolved()
+	state.pending = nil
+	state.suppressed = 0
+	if state.timer != nil {
+		state.timer.Stop()
+		state.timer = nil
+	}
+	return suppressed, true
+}
+
+// 限制间隔结束时，发送因频率限制未发送的最新通知
+func (s *Smtp) flush(key string) {
+	s.mutex.Lock()
+	state, ok := s.states[key]
+	if !ok || state.pending == nil || s.ctx.Err() != nil {
+		s.mutex.Unlock()
+		return
+	}
+	var event = *state.pending
+	var suppressed = state.suppressed - 1
+	state.sentAt = time.Now()
+	state.resolved = event.Resolved()
+	state.pending = nil
+	state.suppressed = 0
+	state.timer = nil
+	s.mutex.Unlock()
+
+	if err := s.mail(s.ctx, event, suppressed); err != nil {
+		log.Printf("notify: %v\n", err)
+	}
+}
+
+// Close 关闭通知器：停止限制间隔结束时的发送，并取消正在进行的发送
+func (s *Smtp) Close() {
+	s.cancel()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, state := range s.states {
+		if state.timer != nil {
+			state.timer.Stop()
+			state.timer = nil
+		}
+		state.pending = nil
+		state.suppressed = 0
+	}
+}
+
+// 渲染并发送邮件
+func (s *Smtp) mail(ctx context.Context, event Event, suppressed int) error {
+	var data = map[string]any{"Event": event, "Suppressed": suppressed}
+	subject, err := tmpl.Text("mail_subject", data)
+	if err != nil {
+		return err
+	}
+	body, err := tmpl.Text("mail", data)
+	if err != nil {
+		return err
+	}
+
+	err = s.send(ctx, subject, body)
+	if err != nil {
+		return fmt.Errorf("smtp: %v", err)
+	}
+	return nil
+}
+
+// 发送邮件，整个会话（连接、握手、发送）不超过发送超时时间，ctx 被取消时立即中断
+func (s *Smtp) send(ctx context.Context, subject, body string) error {
+	var config = s.config
+	var addr = net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+	var tlsConfig = &tls.Config{ServerName: config.Host}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	// 会话过程中 ctx 被取消或超时时关闭连接，中断阻塞的读写
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if config.Tls == TlsImplicit {
+		// 隐式 TLS，通常使用 465 端口
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if config.Tls == TlsStartTls {
+		// STARTTLS，通常使用 587 端口
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if config.User != "" {
+		if err = client.Auth(smtp.PlainAuth("", config.User, config.Passwd, config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(config.From); err != nil {
+		return err
+	}
+	for _, to := range config.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	var header = []string{
+		fmt.Sprintf("From: %s", config.From),
+		fmt.Sprintf("To: %s", strings.Join(config.To, ", ")),
+		fmt.Sprintf("Subject: %s", mime.BEncoding.Encode("UTF-8", subject)),
+		fmt.Sprintf("Date: %s", time.Now().Format(time.RFC1123Z)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	var msg = strings.Join(header, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+	if _, err = writer.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// SmtpConfig 邮件配置
+type SmtpConfig struct {
+	Host     string        // SMTP 服务器主机
+	Port     uint16        // SMTP 服务器端口
+	Tls      string        // TLS 模式：none（不加密）、tls（隐式 TLS）、starttls（STARTTLS）
+	User     string        // 认证用户，为空时不认证
+	Passwd   string        // 认证密码
+	From     string        // 发件人
+	To       []string      // 收件人集
+	Interval time.Duration // 同一实例两封邮件之间的最小间隔
+	Timeout  time.Duration // 发送超时时间
+}
+
+const (
+	TlsNone     = "none"     // 不加密
+	TlsImplicit = "tls"      // 隐式 TLS
+	TlsStartTls = "starttls" // STARTTLS
+)
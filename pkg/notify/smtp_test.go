@@ -0,0 +1,217 @@
+// @author xiangqian
+// @date 2025/08/15 21:10
+package notify
+
+import (
+	"bufio"
+	"context"
+	"gmon/pkg/tmpl"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 模拟 SMTP 服务器，返回收到的邮件集
+func smtpStub(t *testing.T) (string, uint16, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mails = make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+				write("220 localhost")
+				var data strings.Builder
+				var inData = false
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if inData {
+						if line == ".\r\n" {
+							inData = false
+							mails <- data.String()
+							write("250 OK")
+						} else {
+							data.WriteString(line)
+						}
+						continue
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						write("250 localhost")
+					case cmd == "DATA":
+						inData = true
+						write("354 Go ahead")
+					case cmd == "QUIT":
+						write("221 Bye")
+						return
+					default:
+						write("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return host, uint16(p), mails
+}
+
+func TestSmtp(t *testing.T) {
+	if err := tmpl.Init(); err != nil {
+		t.Fatal(err)
+	}
+	host, port, mails := smtpStub(t)
+
+	s := NewSmtp(SmtpConfig{
+		Host:     host,
+		Port:     port,
+		Tls:      TlsNone,
+		From:     "gmon@example.com",
+		To:       []string{"ops@example.com"},
+		Interval: time.Minute,
+	})
+	var now = time.Now()
+	var event = Event{Type: TypeStatus, App: "gweb", Job: "go", Instance: "localhost:58082", OldStatus: "UP", NewStatus: "DOWN", Timestamp: now}
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-mails
+	if !strings.Contains(mail, "To: ops@example.com") || !strings.Contains(mail, "localhost:58082") {
+		t.Fatalf("mail: %s", mail)
+	}
+
+	// 恢复通知在限制间隔内也立即发送
+	event.OldStatus, event.NewStatus, event.Timestamp = "DOWN", "UP", now.Add(10*time.Second)
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case mail = <-mails:
+	case <-time.After(time.Second):
+		t.Fatal("recovery mail not sent")
+	}
+
+	// 限制间隔内的其他通知不发送
+	for i, status := range []string{"DOWN", "UP"} {
+		event.OldStatus, event.NewStatus, event.Timestamp = event.NewStatus, status, now.Add(time.Duration(20+i*10)*time.Second)
+		if err := s.Notify(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case mail = <-mails:
+		t.Fatalf("rate limited mail sent: %s", mail)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 超过限制间隔后发送，并附带未发送的通知数
+	event.OldStatus, event.NewStatus, event.Timestamp = "UP", "DOWN", now.Add(2*time.Minute)
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	mail = <-mails
+	if !strings.Contains(mail, "另有 2 条") {
+		t.Fatalf("mail: %s", mail)
+	}
+}
+
+// 限制间隔结束时发送未发送的最新通知
+func TestSmtpFlush(t *testing.T) {
+	if err := tmpl.Init(); err != nil {
+		t.Fatal(err)
+	}
+	host, port, mails := smtpStub(t)
+
+	s := NewSmtp(SmtpConfig{
+		Host:     host,
+		Port:     port,
+		Tls:      TlsNone,
+		From:     "gmon@example.com",
+		To:       []string{"ops@example.com"},
+		Interval: 200 * time.Millisecond,
+	})
+	var event = Event{Type: TypeStatus, App: "gweb", Job: "go", Instance: "localhost:58082", OldStatus: "DOWN", NewStatus: "UP", Timestamp: time.Now()}
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	<-mails
+
+	// 上线后再次下线，在限制间隔内不发送，间隔结束时发送
+	event.OldStatus, event.NewStatus, event.Timestamp = "UP", "DOWN", time.Now()
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case mail := <-mails:
+		if !strings.Contains(mail, "DOWN") {
+			t.Fatalf("mail: %s", mail)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("suppressed mail not flushed")
+	}
+
+	// 通知器关闭（如重新加载配置后被替换）后不再发送
+	event.OldStatus, event.NewStatus, event.Timestamp = "DOWN", "UP", time.Now()
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	<-mails
+	event.OldStatus, event.NewStatus, event.Timestamp = "UP", "DOWN", time.Now()
+	if err := s.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	select {
+	case mail := <-mails:
+		t.Fatalf("closed notifier sent: %s", mail)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+// 发送不超过 ctx 的截止时间
+func TestSmtpContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// 接受连接但不响应
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	s := NewSmtp(SmtpConfig{Host: host, Port: uint16(p), Tls: TlsNone, Timeout: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var start = time.Now()
+	if err := s.send(ctx, "subject", "body"); err == nil {
+		t.Fatal("send to a hung server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("send ignored ctx: %v", elapsed)
+	}
+}
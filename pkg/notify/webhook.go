@@ -0,0 +1,109 @@
+// @author xiangqian
+// @date 2025/08/12 20:41
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"gmon/pkg/xjson"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook 通知器，以 JSON 格式 POST 事件到配置的地址，失败时按指数退避重试
+type Webhook struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook 创建 Webhook 通知器
+func NewWebhook(config WebhookConfig) *Webhook {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = time.Second
+	}
+	return &Webhook{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (webhook *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := xjson.Serialize(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, url := range webhook.config.Urls {
+		if err = webhook.post(ctx, url, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("webhook: %v", errs)
+	}
+	return nil
+}
+
+// Close 关闭通知器，Webhook 没有后台发送，无需处理
+func (webhook *Webhook) Close() {}
+
+// 发送请求，失败时按指数退避重试
+func (webhook *Webhook) post(ctx context.Context, url string, body []byte) error {
+	var backoff = webhook.config.Backoff
+	var err error
+	for i := 0; i <= webhook.config.Retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = webhook.do(ctx, url, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// 发送一次请求，返回是否需要重试
+func (webhook *Webhook) do(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhook.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	// 服务端错误和限流时重试，其他客户端错误不重试
+	err = fmt.Errorf("%s: %s", url, resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// WebhookConfig Webhook 配置
+type WebhookConfig struct {
+	Urls    []string      // 地址集
+	Retries int           // 失败重试次数
+	Backoff time.Duration // 首次重试等待时间，之后每次翻倍
+	Timeout time.Duration // 单次请求超时时间
+}
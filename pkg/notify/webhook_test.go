@@ -0,0 +1,70 @@
+// @author xiangqian
+// @date 2025/08/12 21:35
+package notify
+
+import (
+	"context"
+	"gmon/pkg/xjson"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	var requests = 0
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// 前两次请求失败，第三次请求成功
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := xjson.Deserialize(body, &received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(WebhookConfig{Urls: []string{server.URL}, Retries: 3, Backoff: time.Millisecond})
+	var event = Event{
+		Type:      TypeStatus,
+		App:       "gweb",
+		Job:       "go",
+		Instance:  "localhost:58082",
+		OldStatus: "UP",
+		NewStatus: "DOWN",
+		Timestamp: time.Now().Truncate(time.Second),
+		Duration:  60,
+	}
+	if err := webhook.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Fatalf("requests: %d", requests)
+	}
+	if received.Instance != event.Instance || received.NewStatus != "DOWN" || !received.Timestamp.Equal(event.Timestamp) {
+		t.Fatalf("received: %+v", received)
+	}
+}
+
+func TestWebhookClientError(t *testing.T) {
+	var requests = 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	// 客户端错误不重试
+	webhook := NewWebhook(WebhookConfig{Urls: []string{server.URL}, Retries: 3, Backoff: time.Millisecond})
+	if err := webhook.Notify(context.Background(), Event{}); err == nil {
+		t.Fatal("want error")
+	}
+	if requests != 1 {
+		t.Fatalf("requests: %d", requests)
+	}
+}
@@ -0,0 +1,350 @@
+// @author xiangqian
+// @date 2025/08/18 20:35
+package prom
+
+import (
+	"context"
+	"fmt"
+	pkg_api "github.com/prometheus/client_golang/api"
+	pkg_api_v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"gmon/pkg/xtime"
+	"math"
+	"sync"
+	"time"
+)
+
+// Client Prometheus 客户端，对应一个 Prometheus 服务器（数据源）
+type Client struct {
+	name        string         // 数据源名称
+	api         pkg_api_v1.API // Prometheus API
+	upDownCache upDownCache    // 上下线时间缓存
+	health      health         // 健康状态
+}
+
+// NewClient 创建 Prometheus 客户端
+func NewClient(config Config) (*Client, error) {
+	roundTripper, err := newRoundTripper(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := pkg_api.NewClient(pkg_api.Config{
+		Address:      config.Address(),
+		RoundTripper: roundTripper,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		name: config.Name,
+		api:  pkg_api_v1.NewAPI(client),
+	}, nil
+}
+
+// Name 数据源名称
+func (client *Client) Name() string {
+	return client.name
+}
+
+// Ping 查询 Prometheus 自身的状态指标，检查 Prometheus 是否可用
+func (client *Client) Ping() error {
+	ctx, cancel := withTimeout()
+	defer cancel()
+	_, _, err := client.api.Query(ctx, "up", time.Now())
+	return err
+}
+
+func (client *Client) Apps() ([]*App, error) {
+	ctx, cancel := withTimeout()
+	defer cancel()
+
+	// 查询所有服务器
+	targets, err := client.api.Targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := targets.Active
+
+	// 所有实例的上下线时间
+	times, err := client.upDownTimes(active)
+	if err != nil {
+		return nil, err
+	}
+
+	apps := make([]*App, 0, len(active))
+label:
+	for _, act := range active {
+		var appName = string(act.Labels["app"])
+		var instName = string(act.Labels["job"])
+		var instAddr = string(act.Labels["instance"])
+
+		var status Status
+		var tm time.Time
+		var duration time.Duration
+		var upDown = times[upDownKey(instName, instAddr)]
+		if upDown == nil {
+			upDown = &UpDown{}
+		}
+		switch act.Health {
+		case pkg_api_v1.HealthGood:
+			status = StatusUp
+			start := upDown.LastDown
+			if start.IsZero() {
+				start = upDown.FirstUp
+			}
+			tm = start
+			// 缓存期间最近一次在线时间不会更新，使用最近一次抓取时间
+			end := upDown.LastUp
+			if act.LastScrape.After(end) {
+				end = act.LastScrape
+			}
+			duration = end.Sub(start)
+			if start.IsZero() || duration < 0 {
+				duration = 0
+			}
+
+		case pkg_api_v1.HealthBad:
+			status = StatusDown
+			tm = upDown.LastUp
+			if tm.IsZero() {
+				tm = upDown.FirstDown
+			}
+		}
+
+		var instance = &Instance{
+			Source:   client.name,
+			Name:     instName,
+			Addr:     instAddr,
+			Status:   status,
+			Time:     xtime.XTime{Time: tm},
+			Duration: xtime.XDuration{Duration: duration},
+		}
+
+		for _, app := range apps {
+			if app.Name == appName {
+				app.Instances = append(app.Instances, instance)
+				continue label
+			}
+		}
+
+		app := &App{
+			Source:    client.name,
+			Name:      appName,
+			Instances: []*Instance{instance},
+		}
+		apps = append(apps, app)
+	}
+
+	sortApps(apps)
+	return apps, nil
+}
+
+// 上下线时间缓存有效期
+const upDownTTL = time.Minute
+
+// 上下线时间缓存
+type upDownCache struct {
+	mutex     sync.Mutex
+	times     map[string]*UpDown                 // 作业名称,实例地址 -> 上下线时间
+	health    map[string]pkg_api_v1.HealthStatus // 作业名称,实例地址 -> 健康状态
+	expiresAt time.Time                          // 过期时间
+}
+
+// 所有实例的上下线时间，缓存过期或者有实例的健康状态发生变化时重新查询
+func (client *Client) upDownTimes(active []pkg_api_v1.ActiveTarget) (map[string]*UpDown, error) {
+	client.upDownCache.mutex.Lock()
+	defer client.upDownCache.mutex.Unlock()
+
+	var health = make(map[string]pkg_api_v1.HealthStatus, len(active))
+	var changed = len(active) != len(client.upDownCache.health)
+	for _, act := range active {
+		var key = upDownKey(string(act.Labels["job"]), string(act.Labels["instance"]))
+		health[key] = act.Health
+		if client.upDownCache.health[key] != act.Health {
+			changed = true
+		}
+	}
+
+	if !changed && client.upDownCache.times != nil && time.Now().Before(client.upDownCache.expiresAt) {
+		return client.upDownCache.times, nil
+	}
+
+	times, err := client.UpDownTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	client.upDownCache.times = times
+	client.upDownCache.health = health
+	client.upDownCache.expiresAt = time.Now().Add(upDownTTL)
+	return times, nil
+}
+
+// UpDownTimes 查询所有实例的上下线时间，按作业名称和实例地址分组，共 4 次查询
+func (client *Client) UpDownTimes() (map[string]*UpDown, error) {
+	var times = make(map[string]*UpDown)
+	for _, query := range []struct {
+		expr string
+		set  func(upDown *UpDown, tm time.Time)
+	}{
+		// 最早一次在线时间
+		{`min by (job, instance) (min_over_time(timestamp(up == 1)[15d:]))`, func(upDown *UpDown, tm time.Time) { upDown.FirstUp = tm }},
+		// 最近一次在线时间
+		{`max by (job, instance) (max_over_time(timestamp(up == 1)[15d:]))`, func(upDown *UpDown, tm time.Time) { upDown.LastUp = tm }},
+		// 最早一次离线时间
+		{`min by (job, instance) (min_over_time(timestamp(up == 0)[15d:]))`, func(upDown *UpDown, tm time.Time) { upDown.FirstDown = tm }},
+		// 最近一次离线时间
+		{`max by (job, instance) (max_over_time(timestamp(up == 0)[15d:]))`, func(upDown *UpDown, tm time.Time) { upDown.LastDown = tm }},
+	} {
+		vector, err := client.Vector(query.expr)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, samp := range vector {
+			var key = upDownKey(string(samp.Metric["job"]), string(samp.Metric["instance"]))
+			upDown, ok := times[key]
+			if !ok {
+				upDown = &UpDown{}
+				times[key] = upDown
+			}
+			query.set(upDown, time.Unix(int64(samp.Value), 0))
+		}
+	}
+	return times, nil
+}
+
+func upDownKey(name, addr string) string {
+	return fmt.Sprintf("%s,%s", name, addr)
+}
+
+// LastSample 最新采样
+func (client *Client) LastSample(expr string) (*Sample, error) {
+	vector, err := client.Vector(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var sample *Sample = nil
+	for _, samp := range vector {
+		metric := samp.Metric
+		var instAddr = string(metric["instance"])
+		var name = string(metric["name"])
+		var key = sampleKey(client.name, instAddr, name)
+		var value = float64(samp.Value)
+		if sample == nil {
+			sample = &Sample{
+				Timestamp: int64(samp.Timestamp),
+				Value:     make(map[string]float64),
+			}
+		}
+		sample.Value[key] = value
+	}
+	return sample, nil
+}
+
+func (client *Client) Vector(expr string) (model.Vector, error) {
+	value, err := client.Query(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert result to vector")
+	}
+
+	return vector, nil
+}
+
+func (client *Client) Query(expr string) (model.Value, error) {
+	ctx, cancel := withTimeout()
+	defer cancel()
+
+	// PromQL 的 [range:offset] 语法：[查询的时间窗口长度, 相对于评估时间点的偏移量]
+	// 计算方式：查询时间范围 = [评估时间 - range - offset, 评估时间 - offset]
+	value, _, err := client.api.Query(ctx,
+		expr,       // 表达式
+		time.Now()) // 评估时间
+	return value, err
+}
+
+// RangeSample 区间采样，各系列的值与时间戳对齐，缺失的值为 null
+func (client *Client) RangeSample(expr string, start, end time.Time, step time.Duration) (*Samples, error) {
+	// 开始时间按步长对齐，使相邻两次查询的时间戳一致
+	start = start.Truncate(step)
+	matrix, err := client.Matrix(expr, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	// 时间戳（毫秒）
+	var timestamps []int64
+	for tm := start; !tm.After(end); tm = tm.Add(step) {
+		timestamps = append(timestamps, tm.UnixMilli())
+	}
+
+	var samples = &Samples{
+		Timestamps: timestamps,
+		Values:     make(map[string][]*float64),
+	}
+	for _, stream := range matrix {
+		metric := stream.Metric
+		var instAddr = string(metric["instance"])
+		var name = string(metric["name"])
+		var key = sampleKey(client.name, instAddr, name)
+		values, ok := samples.Values[key]
+		if !ok {
+			values = make([]*float64, len(timestamps))
+			samples.Values[key] = values
+		}
+		for _, pair := range stream.Values {
+			var i = int((pair.Timestamp.Time().Sub(start) + step/2) / step)
+			if i < 0 || i >= len(values) {
+				continue
+			}
+			var value = float64(pair.Value)
+			// JSON 不支持 NaN 和 Inf
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			values[i] = &value
+		}
+	}
+	return samples, nil
+}
+
+func (client *Client) Matrix(expr string, start, end time.Time, step time.Duration) (model.Matrix, error) {
+	value, err := client.QueryRange(expr, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert result to matrix")
+	}
+
+	return matrix, nil
+}
+
+func (client *Client) QueryRange(expr string, start, end time.Time, step time.Duration) (model.Value, error) {
+	ctx, cancel := withTimeout()
+	defer cancel()
+
+	value, _, err := client.api.QueryRange(ctx,
+		expr, // 表达式
+		pkg_api_v1.Range{
+			Start: start, // 开始时间
+			End:   end,   // 结束时间
+			Step:  step,  // 步长
+		})
+	return value, err
+}
+
+func withTimeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
+}
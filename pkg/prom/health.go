@@ -0,0 +1,143 @@
+// @author xiangqian
+// @date 2025/08/24 10:12
+package prom
+
+import (
+	"context"
+	"gmon/pkg/xtime"
+	"log"
+	"sync"
+	"time"
+)
+
+// 数据源可用时的检查间隔
+const healthInterval = 15 * time.Second
+
+// 数据源不可用时的最小重试间隔，之后每次翻倍
+const minBackoff = time.Second
+
+// 数据源不可用时的最大重试间隔
+const maxBackoff = time.Minute
+
+// Run 定时检查所有数据源是否可用，数据源不可用时按指数退避重试，直到 ctx 被取消；
+// 重新加载配置后改为检查新的客户端集
+func Run(ctx context.Context) {
+	// 忽略启动前 Init 发出的通知
+	select {
+	case <-reloaded:
+	default:
+	}
+
+	for {
+		watchCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		for _, client := range Clients() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.watch(watchCtx)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-reloaded:
+		}
+		cancel()
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Healths 所有数据源的健康状态
+func Healths() []Health {
+	var clients = Clients()
+	var healths = make([]Health, 0, len(clients))
+	for _, client := range clients {
+		healths = append(healths, client.Health())
+	}
+	return healths
+}
+
+// 检查数据源是否可用
+func (client *Client) watch(ctx context.Context) {
+	var backoff = minBackoff
+	for {
+		var wait = healthInterval
+		if client.check() != nil {
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			backoff = minBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// 查询 Prometheus 自身的状态指标，并更新健康状态
+func (client *Client) check() error {
+	err := client.Ping()
+
+	client.health.mutex.Lock()
+	defer client.health.mutex.Unlock()
+
+	var up = err == nil
+	if up != client.health.up || client.health.since.IsZero() {
+		if up {
+			log.Printf("prom %s: available\n", client.name)
+		} else {
+			log.Printf("prom %s: unavailable: %v\n", client.name, err)
+		}
+		client.health.up = up
+		client.health.since = time.Now()
+	}
+	client.health.err = err
+	return err
+}
+
+// Healthy 数据源是否可用
+func (client *Client) Healthy() bool {
+	client.health.mutex.RLock()
+	defer client.health.mutex.RUnlock()
+
+	return client.health.up
+}
+
+// Health 数据源健康状态
+func (client *Client) Health() Health {
+	client.health.mutex.RLock()
+	defer client.health.mutex.RUnlock()
+
+	var health = Health{
+		Source: client.name,
+		Up:     client.health.up,
+		Since:  xtime.XTime{Time: client.health.since},
+	}
+	if client.health.err != nil {
+		health.Error = client.health.err.Error()
+	}
+	return health
+}
+
+// 健康状态
+type health struct {
+	mutex sync.RWMutex // 读写互斥锁
+	up    bool         // 是否可用
+	since time.Time    // 当前状态的开始时间
+	err   error        // 最近一次检查的错误
+}
+
+// Health 数据源健康状态
+type Health struct {
+	Source string      `json:"source"`          // 数据源名称
+	Up     bool        `json:"up"`              // 是否可用
+	Since  xtime.XTime `json:"since"`           // 当前状态的开始时间
+	Error  string      `json:"error,omitempty"` // 最近一次检查的错误
+}
@@ -0,0 +1,49 @@
+// @author xiangqian
+// @date 2025/08/02 10:21
+package prom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 指标目录
+var metrics []Metric
+
+// Metrics 指标目录
+func Metrics() []Metric {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	return metrics
+}
+
+// Expr 将指标目录合并为一个 PromQL 表达式，每个指标系列通过 name 标签区分
+func Expr(metrics []Metric) string {
+	var expr = make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		expr = append(expr, metric.LabelExpr())
+	}
+	return strings.Join(expr, " or ")
+}
+
+// Metric 指标
+type Metric struct {
+	Job   string `json:"job"`   // 作业名称（Prometheus job 标签）
+	Name  string `json:"name"`  // 系列名称，如：cpu_usage、mem_used_bytes、mem_used_percent
+	Expr  string `json:"-"`     // PromQL 表达式，$job 会被替换为作业名称，结果需包含 job、instance 标签
+	Unit  string `json:"unit"`  // 单位：percent（百分比）、bytes（字节）、bytes/s（字节每秒）、空（数值）
+	Label string `json:"label"` // 图表标签
+	Axis  string `json:"axis"`  // 图表 Y 轴：left（左侧）、right（右侧）
+	Chart string `json:"chart"` // 图表标题，相同标题的系列绘制在同一图表中
+}
+
+// JobExpr 替换作业名称后的 PromQL 表达式
+func (metric Metric) JobExpr() string {
+	return strings.ReplaceAll(metric.Expr, "$job", metric.Job)
+}
+
+// LabelExpr 添加 name 标签后的 PromQL 表达式
+func (metric Metric) LabelExpr() string {
+	return fmt.Sprintf(`label_replace(%s, "name", "%s", "", "")`, metric.JobExpr(), metric.Name)
+}
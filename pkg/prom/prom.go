@@ -3,15 +3,16 @@
 package prom
 
 import (
-	"context"
+	"errors"
 	"fmt"
-	pkg_api "github.com/prometheus/client_golang/api"
-	pkg_api_v1 "github.com/prometheus/client_golang/api/prometheus/v1"
-	"github.com/prometheus/common/model"
 	"gmon/pkg/xtime"
 	"log"
 	"math"
+	"net"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,96 +26,168 @@ import (
 // 导航到 Status -> Runtime & Build Information
 // 查找 Storage retention	15d
 
-var api pkg_api_v1.API
+// 客户端集
+var clients []*Client
 
-func Init(config Config) error {
-	// 创建 Prometheus 客户端
-	client, err := pkg_api.NewClient(pkg_api.Config{
-		Address: fmt.Sprintf("http://%s:%d", config.Host, config.Port),
-	})
-	if err != nil {
-		return err
+// 读写互斥锁，保护客户端集和指标目录，重新加载配置时替换
+var rwMutex sync.RWMutex
+
+// 客户端集替换通知，Run 收到通知后重新检查新的客户端集
+var reloaded = make(chan struct{}, 1)
+
+// Init 初始化 Prometheus 客户端集和指标目录，数据源不可用时不返回错误，由 Run 在后台重试。
+// 重新加载配置时再次调用，替换客户端集和指标目录
+func Init(configs []Config, metricArr []Metric) error {
+	var arr = make([]*Client, 0, len(configs))
+	for _, config := range configs {
+		// 创建 Prometheus 客户端
+		client, err := NewClient(config)
+		if err != nil {
+			return fmt.Errorf("%s: %v", config.Name, err)
+		}
+
+		// 查询 Prometheus 自身的状态指标
+		client.check()
+		arr = append(arr, client)
 	}
 
-	api = pkg_api_v1.NewAPI(client)
+	rwMutex.Lock()
+	clients = arr
+	metrics = metricArr
+	rwMutex.Unlock()
 
-	// 查询 Prometheus 自身的状态指标
-	ctx, cancel := withTimeout()
-	defer cancel()
-	_, _, err = api.Query(ctx, "up", time.Now())
-	if err != nil {
-		return err
+	select {
+	case reloaded <- struct{}{}:
+	default:
 	}
 	return nil
 }
 
-func Apps() ([]*App, error) {
-	ctx, cancel := withTimeout()
-	defer cancel()
-
-	// 查询所有服务器
-	targets, err := api.Targets(ctx)
-	if err != nil {
-		return nil, err
+// Check 校验数据源配置，不替换当前的客户端集
+func Check(configs []Config) error {
+	for _, config := range configs {
+		if _, err := NewClient(config); err != nil {
+			return fmt.Errorf("%s: %v", config.Name, err)
+		}
 	}
+	return nil
+}
 
-	active := targets.Active
-	apps := make([]*App, 0, len(active))
-label:
-	for _, act := range active {
-		var appName = string(act.Labels["app"])
-		var instName = string(act.Labels["job"])
-		var instAddr = string(act.Labels["instance"])
-
-		var status Status
-		var tm time.Time
-		var duration time.Duration
-		switch act.Health {
-		case pkg_api_v1.HealthGood:
-			status = StatusUp
-			start, _ := LastDownTime(instName, instAddr)
-			if start.IsZero() {
-				start, _ = FirstUpTime(instName, instAddr)
-			}
-			tm = start
-			end, _ := LastUpTime(instName, instAddr)
-			duration = end.Sub(start)
-			if duration < 0 {
-				duration = 0
-			}
+// Clients 客户端集
+func Clients() []*Client {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
 
-		case pkg_api_v1.HealthBad:
-			status = StatusDown
-			tm, _ = LastUpTime(instName, instAddr)
-			if tm.IsZero() {
-				tm, _ = FirstDownTime(instName, instAddr)
-			}
+	return clients
+}
+
+// GetClient 根据数据源名称获取客户端
+func GetClient(name string) *Client {
+	for _, client := range Clients() {
+		if client.name == name {
+			return client
 		}
+	}
+	return nil
+}
 
-		var instance = &Instance{
-			Name:     instName,
-			Addr:     instAddr,
-			Status:   status,
-			Time:     xtime.XTime{Time: tm},
-			Duration: xtime.XDuration{Duration: duration},
+// Apps 合并所有可用数据源的应用，每个应用和实例都标记所属数据源；部分数据源查询失败时记录日志，全部查询失败时返回错误
+func Apps() ([]*App, error) {
+	var apps []*App
+	var errs []error
+	var healthy = healthyClients()
+	for _, client := range healthy {
+		arr, err := client.Apps()
+		if err != nil {
+			log.Printf("prom %s: %v\n", client.name, err)
+			errs = append(errs, fmt.Errorf("%s: %v", client.name, err))
+			continue
 		}
+		apps = append(apps, arr...)
+	}
+	if len(errs) > 0 && len(errs) == len(healthy) {
+		return nil, errors.Join(errs...)
+	}
 
-		for _, app := range apps {
-			if app.Name == appName {
-				app.Instances = append(app.Instances, instance)
-				continue label
-			}
+	sortApps(apps)
+	return apps, nil
+}
+
+// LastSample 合并所有可用数据源的最新采样
+func LastSample(expr string) (*Sample, error) {
+	log.Printf("expr: %s\n", expr)
+
+	var sample *Sample = nil
+	var errs []error
+	var healthy = healthyClients()
+	for _, client := range healthy {
+		samp, err := client.LastSample(expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", client.name, err))
+			continue
+		}
+		if samp == nil {
+			continue
+		}
+		if sample == nil {
+			sample = samp
+			continue
+		}
+		for key, value := range samp.Value {
+			sample.Value[key] = value
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(healthy) {
+		return nil, errors.Join(errs...)
+	}
+	return sample, nil
+}
+
+// LastRangeSample 合并所有可用数据源最近一段时间的区间采样
+func LastRangeSample(expr string, duration, step time.Duration) (*Samples, error) {
+	// 各数据源使用相同的结束时间，开始时间按步长对齐后时间戳一致
+	end := time.Now()
+
+	var samples *Samples = nil
+	var errs []error
+	var healthy = healthyClients()
+	for _, client := range healthy {
+		samps, err := client.RangeSample(expr, end.Add(-duration), end, step)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", client.name, err))
+			continue
 		}
+		if samples == nil {
+			samples = samps
+			continue
+		}
+		for key, values := range samps.Values {
+			samples.Values[key] = values
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(healthy) {
+		return nil, errors.Join(errs...)
+	}
+	return samples, nil
+}
 
-		app := &App{
-			Name:      appName,
-			Instances: []*Instance{instance},
+// 可用的客户端集
+func healthyClients() []*Client {
+	var arr []*Client
+	for _, client := range Clients() {
+		if client.Healthy() {
+			arr = append(arr, client)
 		}
-		apps = append(apps, app)
 	}
+	return arr
+}
 
-	// 排序
-	sort.Slice(apps, func(i, j int) bool {
+// 应用排序：先按数据源配置顺序，再按作业名称；实例按地址排序
+func sortApps(apps []*App) {
+	sort.SliceStable(apps, func(i, j int) bool {
+		if apps[i].Source != apps[j].Source {
+			return sourceIndex(apps[i].Source) < sourceIndex(apps[j].Source)
+		}
 		return number(apps[i].Instances[0].Name) < number(apps[j].Instances[0].Name)
 	})
 	for _, app := range apps {
@@ -122,8 +195,42 @@ label:
 			return app.Instances[i].Addr < app.Instances[j].Addr
 		})
 	}
+}
 
-	return apps, nil
+// 数据源序号，与配置顺序一致
+func sourceIndex(name string) int {
+	var clients = Clients()
+	for i, client := range clients {
+		if client.name == name {
+			return i
+		}
+	}
+	return len(clients)
+}
+
+// 采样键：数据源名称,实例地址,系列名称
+func sampleKey(source, addr, name string) string {
+	return fmt.Sprintf("%s,%s,%s", source, addr, name)
+}
+
+// GroupBySource 按数据源分组应用
+func GroupBySource(apps []*App) []*Source {
+	var sources []*Source
+	for _, app := range apps {
+		var source *Source
+		for _, s := range sources {
+			if s.Name == app.Source {
+				source = s
+				break
+			}
+		}
+		if source == nil {
+			source = &Source{Name: app.Source}
+			sources = append(sources, source)
+		}
+		source.Apps = append(source.Apps, app)
+	}
+	return sources
 }
 
 func number(name string) uint8 {
@@ -147,131 +254,22 @@ func number(name string) uint8 {
 	}
 }
 
-// FirstUpTime 应用实例最早一次在线时间
-func FirstUpTime(name, addr string) (time.Time, error) {
-	vector, err := Vector(fmt.Sprintf(`min_over_time(timestamp(up{job="%s", instance="%s"} == 1)[15d:])`, name, addr))
-	if err != nil {
-		return time.Time{}, nil
-	}
-
-	if vector.Len() == 0 {
-		return time.Time{}, nil
-	}
-
-	timestamp := int64(vector[0].Value)
-	return time.Unix(timestamp, 0), nil
-}
-
-// LastUpTime 应用实例最近一次在线时间
-func LastUpTime(name, addr string) (time.Time, error) {
-	vector, err := Vector(fmt.Sprintf(`max_over_time(timestamp(up{job="%s", instance="%s"} == 1)[15d:])`, name, addr))
-	if err != nil {
-		return time.Time{}, nil
-	}
-
-	if vector.Len() == 0 {
-		return time.Time{}, nil
-	}
-
-	timestamp := int64(vector[0].Value)
-	return time.Unix(timestamp, 0), nil
-}
-
-// FirstDownTime 应用实例最早一次离线时间
-func FirstDownTime(name, addr string) (time.Time, error) {
-	vector, err := Vector(fmt.Sprintf(`min_over_time(timestamp(up{job="%s", instance="%s"} == 0)[15d:])`, name, addr))
-	if err != nil {
-		return time.Time{}, nil
-	}
-
-	if vector.Len() == 0 {
-		return time.Time{}, nil
-	}
-
-	timestamp := int64(vector[0].Value)
-	return time.Unix(timestamp, 0), nil
-}
-
-// LastDownTime 应用实例最近一次离线时间
-func LastDownTime(name, addr string) (time.Time, error) {
-	vector, err := Vector(fmt.Sprintf(`max_over_time(timestamp(up{job="%s", instance="%s"} == 0)[15d:])`, name, addr))
-	if err != nil {
-		return time.Time{}, nil
-	}
-
-	if vector.Len() == 0 {
-		return time.Time{}, nil
-	}
-
-	timestamp := int64(vector[0].Value)
-	return time.Unix(timestamp, 0), nil
-}
-
-// LastSample 最新采样
-func LastSample(expr string) (*Sample, error) {
-	log.Printf("expr: %s\n", expr)
-
-	vector, err := Vector(expr)
-	if err != nil {
-		return nil, err
-	}
-
-	var sample *Sample = nil
-	for _, samp := range vector {
-		metric := samp.Metric
-		var instAddr = string(metric["instance"])
-		var name = string(metric["name"])
-		var key = fmt.Sprintf("%s,%s", instAddr, name)
-		var value = float64(samp.Value)
-		if sample == nil {
-			sample = &Sample{
-				Timestamp: int64(samp.Timestamp),
-				Value:     make(map[string]float64),
-			}
-		}
-		sample.Value[key] = value
-	}
-	return sample, nil
-}
-
-func Vector(expr string) (model.Vector, error) {
-	value, err := Query(expr)
-	if err != nil {
-		return nil, err
-	}
-
-	vector, ok := value.(model.Vector)
-	if !ok {
-		return nil, fmt.Errorf("cannot convert result to vector")
-	}
-
-	return vector, nil
-}
-
-func Query(expr string) (model.Value, error) {
-	ctx, cancel := withTimeout()
-	defer cancel()
-
-	// PromQL 的 [range:offset] 语法：[查询的时间窗口长度, 相对于评估时间点的偏移量]
-	// 计算方式：查询时间范围 = [评估时间 - range - offset, 评估时间 - offset]
-	value, _, err := api.Query(ctx,
-		expr,       // 表达式
-		time.Now()) // 评估时间
-	return value, err
-}
-
-func withTimeout() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), 5*time.Second)
+// Source 数据源
+type Source struct {
+	Name string `json:"name"` // 名称
+	Apps []*App `json:"apps"` // 应用集
 }
 
 // App 应用
 type App struct {
+	Source    string      `json:"source"`    // 数据源名称
 	Name      string      `json:"name"`      // 名称
 	Instances []*Instance `json:"instances"` // 实例集
 }
 
 // Instance 实例
 type Instance struct {
+	Source   string          `json:"source"`   // 数据源名称
 	Name     string          `json:"name"`     // 名称
 	Addr     string          `json:"addr"`     // 地址
 	Status   Status          `json:"status"`   // 状态
@@ -279,12 +277,26 @@ type Instance struct {
 	Duration xtime.XDuration `json:"duration"` // 在线持续时间
 }
 
+// UpDown 上下线时间
+type UpDown struct {
+	FirstUp   time.Time // 最早一次在线时间
+	LastUp    time.Time // 最近一次在线时间
+	FirstDown time.Time // 最早一次离线时间
+	LastDown  time.Time // 最近一次离线时间
+}
+
 // Sample 采样
 type Sample struct {
 	Timestamp int64              `json:"timestamp"`
 	Value     map[string]float64 `json:"value"`
 }
 
+// Samples 采样集
+type Samples struct {
+	Timestamps []int64               `json:"timestamps"` // 时间戳（毫秒）
+	Values     map[string][]*float64 `json:"values"`     // 数据源名称,实例地址,系列名称 -> 值集
+}
+
 type Status byte
 
 const (
@@ -309,6 +321,32 @@ func (status Status) String() string {
 
 // Config Prometheus 配置
 type Config struct {
-	Host string // Prometheus 主机
-	Port uint16 // Prometheus 端口
+	Name   string // 数据源名称
+	Scheme string // 协议：http、https
+	Host   string // Prometheus 主机
+	Port   uint16 // Prometheus 端口
+	Path   string // 路径前缀，如 Prometheus 部署在反向代理的 /prometheus 路径下
+
+	CaFile             string // CA 证书文件，为空时使用系统 CA 证书
+	CertFile           string // 客户端证书文件（双向 TLS）
+	KeyFile            string // 客户端私钥文件（双向 TLS）
+	InsecureSkipVerify bool   // 是否跳过服务端证书校验
+
+	User      string // Basic 认证用户
+	Passwd    string // Basic 认证密码
+	Token     string // Bearer Token
+	TokenFile string // Bearer Token 文件，每次请求时读取，优先于 Token
+}
+
+// Address Prometheus 地址
+func (config Config) Address() string {
+	var scheme = config.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	var path = strings.TrimRight(config.Path, "/")
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))), path)
 }
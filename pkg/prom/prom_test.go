@@ -10,7 +10,7 @@ import (
 )
 
 func TestInit(t *testing.T) {
-	err := Init(Config{"localhost", 9090})
+	err := Init([]Config{{Name: "default", Host: "localhost", Port: 9090}}, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -38,7 +38,7 @@ func TestQuery(t *testing.T) {
 
 	// go_memstats_sys_bytes{job="prom"}[1h]
 
-	value, err := Query(expr)
+	value, err := Clients()[0].Query(expr)
 	if err != nil {
 		panic(err)
 	}
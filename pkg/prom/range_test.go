@@ -0,0 +1,179 @@
+// @author xiangqian
+// @date 2025/08/03 11:02
+package prom
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 模拟 Prometheus 服务器
+func stub(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := strconv.ParseUint(port, 10, 16)
+	err = Init([]Config{{Name: "default", Host: host, Port: uint16(p)}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRangeSample(t *testing.T) {
+	stub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/query" {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+		// 第 1、3 个数据点，第 2 个数据点缺失
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"instance":"localhost:9100","name":"cpu_usage"},"values":[[60,"1.5"],[180,"3.5"]]}
+		]}}`))
+	})
+
+	samples, err := Clients()[0].RangeSample("up", time.Unix(60, 0), time.Unix(180, 0), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples.Timestamps) != 3 || samples.Timestamps[0] != 60000 {
+		t.Fatalf("timestamps: %v", samples.Timestamps)
+	}
+	values := samples.Values["default,localhost:9100,cpu_usage"]
+	if len(values) != 3 || values[0] == nil || *values[0] != 1.5 || values[1] != nil || values[2] == nil || *values[2] != 3.5 {
+		t.Fatalf("values: %v", values)
+	}
+}
+
+func TestAppsUpDown(t *testing.T) {
+	var queries = 0
+	stub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			w.Write([]byte(`{"status":"success","data":{"activeTargets":[
+				{"labels":{"app":"a","job":"go","instance":"h1:1"},"health":"up","lastScrape":"1970-01-01T00:16:40Z"},
+				{"labels":{"app":"a","job":"go","instance":"h2:1"},"health":"down","lastScrape":"1970-01-01T00:16:40Z"}
+			],"droppedTargets":[]}}`))
+		case "/api/v1/query":
+			queries++
+			r.ParseForm()
+			var result = `[]`
+			switch r.Form.Get("query") {
+			case `min by (job, instance) (min_over_time(timestamp(up == 1)[15d:]))`:
+				result = `[{"metric":{"job":"go","instance":"h1:1"},"value":[1000,"100"]},{"metric":{"job":"go","instance":"h2:1"},"value":[1000,"100"]}]`
+			case `max by (job, instance) (max_over_time(timestamp(up == 1)[15d:]))`:
+				result = `[{"metric":{"job":"go","instance":"h1:1"},"value":[1000,"900"]},{"metric":{"job":"go","instance":"h2:1"},"value":[1000,"500"]}]`
+			case `max by (job, instance) (max_over_time(timestamp(up == 0)[15d:]))`:
+				result = `[{"metric":{"job":"go","instance":"h1:1"},"value":[1000,"400"]}]`
+			}
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":` + result + `}}`))
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		apps, err := Apps()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(apps) != 1 || apps[0].Source != "default" || len(apps[0].Instances) != 2 {
+			t.Fatalf("apps: %+v", apps)
+		}
+
+		// 在线：最近一次离线时间至最近一次抓取时间
+		h1 := apps[0].Instances[0]
+		if h1.Status != StatusUp || h1.Time.Unix() != 400 || h1.Duration.Duration != 600*time.Second {
+			t.Fatalf("h1: %+v", h1)
+		}
+
+		// 离线：最近一次在线时间
+		h2 := apps[0].Instances[1]
+		if h2.Status != StatusDown || h2.Time.Unix() != 500 {
+			t.Fatalf("h2: %+v", h2)
+		}
+	}
+
+	// 第二次使用缓存：Init 1 次 + 上下线时间 4 次
+	if queries != 5 {
+		t.Fatalf("queries: %d", queries)
+	}
+}
+
+func TestTlsBearerToken(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prometheus/api/v1/query" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	// 服务端证书作为 CA 证书
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	p, _ := strconv.ParseUint(port, 10, 16)
+	var config = Config{Name: "default", Scheme: "https", Host: host, Port: uint16(p), Path: "/prometheus/", CaFile: caFile, TokenFile: tokenFile}
+	if err := Init([]Config{config}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !Clients()[0].Healthy() {
+		t.Fatalf("health: %+v", Clients()[0].Health())
+	}
+
+	// Token 错误：数据源不可用，但不影响初始化
+	config.TokenFile, config.Token = "", "wrong"
+	if err := Init([]Config{config}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if health := Clients()[0].Health(); health.Up || health.Error == "" {
+		t.Fatalf("health: %+v", health)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	// 未监听的端口
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	listener.Close()
+
+	err = Init([]Config{{Name: "default", Host: "127.0.0.1", Port: uint16(addr.Port)}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	healths := Healths()
+	if len(healths) != 1 || healths[0].Up || healths[0].Since.IsZero() {
+		t.Fatalf("healths: %+v", healths)
+	}
+
+	// 不可用的数据源被跳过
+	apps, err := Apps()
+	if err != nil || len(apps) != 0 {
+		t.Fatalf("apps: %v, %v", apps, err)
+	}
+}
@@ -0,0 +1,95 @@
+// @author xiangqian
+// @date 2025/08/21 20:48
+package prom
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	pkg_api "github.com/prometheus/client_golang/api"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// 创建连接 Prometheus 的 RoundTripper，支持 TLS（CA 证书、客户端证书）、Basic 认证和 Bearer Token 认证
+func newRoundTripper(config Config) (http.RoundTripper, error) {
+	tlsConfig, err := newTlsConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := pkg_api.DefaultRoundTripper.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if config.User == "" && config.Token == "" && config.TokenFile == "" {
+		return transport, nil
+	}
+	if config.User != "" && (config.Token != "" || config.TokenFile != "") {
+		return nil, fmt.Errorf("basic auth and bearer token are mutually exclusive")
+	}
+	return &authRoundTripper{config: config, next: transport}, nil
+}
+
+// 创建 TLS 配置
+func newTlsConfig(config Config) (*tls.Config, error) {
+	var tlsConfig = &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	// CA 证书
+	if config.CaFile != "" {
+		pem, err := os.ReadFile(config.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", config.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// 客户端证书
+	if config.CertFile != "" || config.KeyFile != "" {
+		if config.CertFile == "" || config.KeyFile == "" {
+			return nil, fmt.Errorf("cert_file and key_file must be configured together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// 认证 RoundTripper，为每个请求添加 Authorization 请求头
+type authRoundTripper struct {
+	config Config
+	next   http.RoundTripper
+}
+
+func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper 不能修改原请求
+	req = req.Clone(req.Context())
+
+	config := rt.config
+	if config.User != "" {
+		req.SetBasicAuth(config.User, config.Passwd)
+		return rt.next.RoundTrip(req)
+	}
+
+	var token = config.Token
+	if config.TokenFile != "" {
+		// 每次请求都读取文件，支持 Token 轮换
+		buf, err := os.ReadFile(config.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(buf))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,223 @@
+// @author xiangqian
+// @date 2025/08/30 15:06
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gmon/pkg/xhttp"
+	"golang.org/x/oauth2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDC 单点登录：
+// 1. 重定向到身份提供方（IdP）的授权页面，携带 state、nonce 和 PKCE 参数
+// 2. 用户在 IdP 登录后，IdP 回调 gmon，gmon 使用授权码换取 ID Token
+// 3. 校验 ID Token 的签名、签发者、受众、有效期和 nonce，根据声明映射用户名和角色
+
+// 单点登录配置
+var config Config
+
+// 互斥锁
+var mutex sync.Mutex
+
+// 身份提供方，首次使用时通过发现文档（/.well-known/openid-configuration）获取，
+// 身份提供方不可用时不影响 gmon 启动，下次登录时重试
+var provider *oidc.Provider
+
+// 请求身份提供方的超时时间
+const timeout = 10 * time.Second
+
+// Init 初始化单点登录
+func Init(c Config) error {
+	if c.Issuer == "" {
+		config = c
+		return nil
+	}
+	if c.ClientId == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if c.RedirectUrl == "" {
+		return fmt.Errorf("redirect_url is required")
+	}
+	if c.UserClaim == "" {
+		c.UserClaim = "preferred_username"
+	}
+	if c.RoleClaim == "" {
+		c.RoleClaim = "groups"
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	} else if !slices.Contains(c.Scopes, oidc.ScopeOpenID) {
+		c.Scopes = append([]string{oidc.ScopeOpenID}, c.Scopes...)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	config = c
+	provider = nil
+	return nil
+}
+
+// Enabled 是否启用单点登录
+func Enabled() bool {
+	return config.Issuer != ""
+}
+
+// Name 身份提供方名称，用于登录页面的按钮
+func Name() string {
+	if config.Name == "" {
+		return "SSO"
+	}
+	return config.Name
+}
+
+// AuthCodeUrl 身份提供方的授权地址
+func AuthCodeUrl(state, nonce, verifier string) (string, error) {
+	oauth2Config, err := getOauth2Config()
+	if err != nil {
+		return "", err
+	}
+	return oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 使用授权码换取并校验 ID Token，返回登录用户
+func Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	oauth2Config, err := getOauth2Config()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange: %v", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no id_token in token response")
+	}
+
+	p, err := getProvider()
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := p.Verifier(&oidc.Config{ClientID: config.ClientId}).Verify(ctx, rawIdToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id_token: %v", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("verify id_token: invalid nonce")
+	}
+
+	var claims map[string]any
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, err
+	}
+	return identity(idToken.Subject, claims)
+}
+
+// 根据 ID Token 的声明映射用户名和角色
+func identity(subject string, claims map[string]any) (*Identity, error) {
+	user, _ := claims[config.UserClaim].(string)
+	if user == "" {
+		user = subject
+	}
+
+	var values = claimValues(claims[config.RoleClaim])
+	var matched = func(groups []string) bool {
+		for _, group := range groups {
+			if slices.Contains(values, group) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case matched(config.AdminGroups):
+		return &Identity{User: user, Role: xhttp.RoleAdmin}, nil
+	case len(config.ViewerGroups) == 0 || matched(config.ViewerGroups):
+		return &Identity{User: user, Role: xhttp.RoleViewer}, nil
+	default:
+		return nil, fmt.Errorf("user %s: no matching %s", user, config.RoleClaim)
+	}
+}
+
+// 声明值：字符串、字符串数组或者以逗号分隔的字符串
+func claimValues(claim any) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			values = append(values, strings.TrimSpace(s))
+		}
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+func getOauth2Config() (*oauth2.Config, error) {
+	p, err := getProvider()
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     config.ClientId,
+		ClientSecret: config.ClientSecret,
+		Endpoint:     p.Endpoint(),
+		RedirectURL:  config.RedirectUrl,
+		Scopes:       config.Scopes,
+	}, nil
+}
+
+func getProvider() (*oidc.Provider, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if provider != nil {
+		return provider, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	p, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover %s: %v", config.Issuer, err)
+	}
+	provider = p
+	return provider, nil
+}
+
+// Identity 单点登录用户
+type Identity struct {
+	User string     // 用户名
+	Role xhttp.Role // 角色
+}
+
+// Config 单点登录配置
+type Config struct {
+	Name         string   // 身份提供方名称，用于登录页面的按钮
+	Issuer       string   // 签发者地址，为空时不启用单点登录
+	ClientId     string   // 客户端 ID
+	ClientSecret string   // 客户端密钥
+	RedirectUrl  string   // 回调地址：<gmon 地址><http.prefix>/login/oidc/callback
+	Scopes       []string // 授权范围
+	UserClaim    string   // 用户名声明，默认 preferred_username，不存在时使用 sub
+	RoleClaim    string   // 角色声明，默认 groups
+	AdminGroups  []string // 角色声明包含其中任意值时为管理员
+	ViewerGroups []string // 角色声明包含其中任意值时为只读用户，为空时所有登录用户都是只读用户
+}
@@ -0,0 +1,175 @@
+// @author xiangqian
+// @date 2025/08/30 17:02
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"gmon/pkg/xhttp"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// 模拟身份提供方
+type idp struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	nonce     string         // 授权请求中的 nonce
+	challenge string         // 授权请求中的 PKCE code_challenge
+	claims    map[string]any // ID Token 的附加声明
+}
+
+func newIdp(t *testing.T) *idp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p = &idp{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/auth",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		// 校验授权码和 PKCE
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "code" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		var claims = map[string]any{
+			"iss":   p.server.URL,
+			"sub":   "1001",
+			"aud":   "gmon",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.sign(t, claims),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// 签发 RS256 JWT
+func (p *idp) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "typ": "JWT", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// 模拟授权请求，记录 nonce 和 code_challenge
+func (p *idp) authorize(t *testing.T, nonce, verifier string) {
+	authUrl, err := AuthCodeUrl("state", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != "gmon" || query.Get("state") != "state" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("auth url: %s", authUrl)
+	}
+	p.nonce = query.Get("nonce")
+	p.challenge = query.Get("code_challenge")
+}
+
+func TestExchange(t *testing.T) {
+	p := newIdp(t)
+	err := Init(Config{
+		Issuer:       p.server.URL,
+		ClientId:     "gmon",
+		ClientSecret: "secret",
+		RedirectUrl:  "http://localhost/login/oidc/callback",
+		AdminGroups:  []string{"ops"},
+		ViewerGroups: []string{"dev"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Init(Config{})
+
+	for _, c := range []struct {
+		claims map[string]any
+		user   string
+		role   xhttp.Role
+	}{
+		{map[string]any{"preferred_username": "alice", "groups": []string{"dev", "ops"}}, "alice", xhttp.RoleAdmin},
+		{map[string]any{"preferred_username": "bob", "groups": []string{"dev"}}, "bob", xhttp.RoleViewer},
+		{map[string]any{"groups": "dev"}, "1001", xhttp.RoleViewer},
+		{map[string]any{"preferred_username": "eve", "groups": []string{"sales"}}, "", 0},
+	} {
+		p.claims = c.claims
+		p.authorize(t, "nonce", "verifier-0123456789-0123456789-0123456789")
+		identity, err := Exchange(context.Background(), "code", "nonce", "verifier-0123456789-0123456789-0123456789")
+		if c.role == 0 {
+			if err == nil {
+				t.Fatalf("%v: accepted as %+v", c.claims, identity)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", c.claims, err)
+		}
+		if identity.User != c.user || identity.Role != c.role {
+			t.Fatalf("%v: %+v", c.claims, identity)
+		}
+	}
+
+	// nonce 不一致
+	p.claims = nil
+	p.authorize(t, "other", "verifier-0123456789-0123456789-0123456789")
+	if _, err = Exchange(context.Background(), "code", "nonce", "verifier-0123456789-0123456789-0123456789"); err == nil {
+		t.Fatal("invalid nonce accepted")
+	}
+
+	// PKCE 校验码不一致
+	p.authorize(t, "nonce", "verifier-0123456789-0123456789-0123456789")
+	if _, err = Exchange(context.Background(), "code", "nonce", "other-verifier-0123456789-0123456789-0123"); err == nil {
+		t.Fatal("invalid verifier accepted")
+	}
+}
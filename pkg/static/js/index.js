@@ -2,117 +2,239 @@
 // @date 2025/07/27 15:17
 
 function getElement(instance, name) {
-    let id = `${instance.addr},${name}`;
+    let id = `${instance.source},${instance.addr},${name}`;
     return document.getElementById(id);
 }
 
-document.addEventListener('DOMContentLoaded', function () {
-    let line = null;
-    let indexes = null;
-    let eventSource = new EventSource('/event');
-    eventSource.onmessage = (e) => {
-        let data = JSON.parse(e.data);
-
-        let apps = data.apps;
-        // console.log('apps', apps);
-        for (let app of apps) {
-            for (let instance of app.instances) {
-                let statusElement = getElement(instance, 'status');
-                statusElement.textContent = instance.status;
-                if (instance.status === 'UP') {
-                    statusElement.className = 'status status-ok';
-                } else if (instance.status === 'DOWN') {
-                    statusElement.className = 'status status-error';
-                } else {
-                    statusElement.className = 'status status-unknown';
-                }
+/**
+ * 渲染数据源不可用、采集失败横幅
+ * @param data 采集数据：数据源健康状态集，采集失败时提示页面数据已过期
+ */
+function renderBanner(data) {
+    let banner = document.getElementById('banner');
+    banner.innerHTML = '';
+    if (data.error != null) {
+        let div = document.createElement('div');
+        div.textContent = `${data.time} 采集数据失败，页面数据已过期：${data.error}`;
+        banner.appendChild(div);
+    }
+    for (let health of data.healths || []) {
+        if (!health.up) {
+            let div = document.createElement('div');
+            div.textContent = `Prometheus（${health.source}）自 ${health.since} 起不可用：${health.error || ''}`;
+            banner.appendChild(div);
+        }
+    }
+}
+
+/**
+ * 渲染告警集
+ * @param alerts 告警集
+ */
+function renderAlerts(alerts) {
+    let table = document.getElementById('alerts');
+    let rows = table.rows;
+    // 保留标题行
+    while (rows.length > 1) {
+        table.deleteRow(1);
+    }
+
+    table.hidden = alerts == null || alerts.length === 0;
+    if (table.hidden) {
+        return;
+    }
 
-                let timeElement = getElement(instance, 'time');
-                timeElement.textContent = instance.time;
+    for (let alert of alerts) {
+        let row = table.insertRow();
+
+        let status = document.createElement('span');
+        status.textContent = alert.state;
+        if (alert.state === 'FIRING') {
+            status.className = 'status status-error';
+        } else if (alert.state === 'PENDING') {
+            status.className = 'status status-unknown';
+        } else {
+            status.className = 'status status-ok';
+        }
+        row.insertCell().appendChild(status);
+
+        for (let text of [alert.rule, `${alert.source} ${alert.job} ${alert.instance}`, alert.value.toFixed(2), alert.activeAt]) {
+            let cell = row.insertCell();
+            cell.className = 'text';
+            cell.textContent = text;
+        }
+    }
+}
 
-                let durationElement = getElement(instance, 'duration');
-                durationElement.textContent = instance.duration;
+/**
+ * 获取实例标签
+ * @param apps   应用集
+ * @param source 数据源名称
+ * @param addr   实例地址
+ * @returns {string|null}
+ */
+function getLabel(apps, source, addr) {
+    // 存在多个数据源时，标签中包含数据源名称
+    let multiple = new Set(apps.map(app => app.source)).size > 1;
+    for (let app of apps) {
+        for (let instance of app.instances) {
+            if (instance.source === source && instance.addr === addr) {
+                let name = multiple ? `${source}/${app.name}` : app.name;
+                let arr = addr.split(':');
+                if (arr.length === 2) {
+                    return `${name}-${arr[1]}`;
+                }
+                return name;
             }
         }
+    }
+    return null;
+}
 
-        let sample = data.sample;
-        // console.log('sample', sample);
-        if(sample == null){
-            return;
+/**
+ * 创建图表集
+ * @param apps    应用集
+ * @param metrics 指标目录
+ * @param names   系列名称集（数据源名称,实例地址,系列名称）
+ * @returns {Map} 图表标题 -> {line: 折线图, indexes: 系列名称 -> 系列索引}
+ */
+function newCharts(apps, metrics, names) {
+    // 指标目录：系列名称 -> 指标
+    let metricMap = new Map();
+    for (let metric of metrics) {
+        if (!metricMap.has(metric.name)) {
+            metricMap.set(metric.name, metric);
         }
+    }
 
+    // 按图表分组系列
+    let groups = new Map();
+    for (let name of names) {
+        let arr = name.split(',');
+        let source = arr[0];
+        let addr = arr[1];
+        let metric = metricMap.get(arr[2]);
+        if (metric === undefined) {
+            continue;
+        }
 
-        if (line == null) {
-            indexes = new Map();
-            let index = 0;
-            let series = new Array();
-            for (let name in sample.value) {
-                let ser = null;
-                let arr = name.split(',');
-                let addr = arr[0];
-                let metric = arr[1];
-                if (metric === 'cpu_usage') {
-                    ser = {
-                        label: 'CPU',
-                        scale: YAxis.Left,
-                        format: (u, v) => v === null ? '--' : v.toFixed(2) + '%',
-                        formats: (u, vals) => vals.map(v => v.toFixed(0) + '%'),
-                    };
-                } else if (metric === 'mem_used_percent') {
-                    ser = {
-                        label: 'MEM',
-                        scale: YAxis.Left,
-                        format: (u, v) => v === null ? '--' : v.toFixed(2) + '%',
-                        formats: (u, vals) => vals.map(v => v.toFixed(0) + '%'),
-                    };
-                } else if (metric === 'mem_used_bytes') {
-                    ser = {
-                        label: 'MEM',
-                        scale: YAxis.Right,
-                        format: (u, v) => v === null ? '--' : formatBytes(v, 2),
-                        formats: (u, vals) => vals.map(v => formatBytes(v, 0)),
-                    };
-                }
+        let ser = newSeries(metric);
+        let label = getLabel(apps, source, addr);
+        if (label != null) {
+            ser.label = `${label} ${ser.label}`;
+        }
+
+        let group = groups.get(metric.chart);
+        if (group === undefined) {
+            group = {series: new Array(), indexes: new Map()};
+            groups.set(metric.chart, group);
+        }
+        group.indexes.set(name, group.series.length);
+        group.series.push(ser);
+    }
+
+    let charts = new Map();
+    let element = document.getElementById('chart');
+    for (let [title, group] of groups) {
+        let div = document.createElement('div');
+        element.appendChild(div);
+        charts.set(title, {line: new Line(div, title, 800, 400, group.series), indexes: group.indexes});
+    }
+    return charts;
+}
+
+/**
+ * 使用历史采样填充图表集
+ * @param charts  图表集
+ * @param samples 采样集
+ */
+function fillCharts(charts, samples) {
+    for (let chart of charts.values()) {
+        let values = Array.from({length: chart.indexes.size}, () => null);
+        for (let [name, index] of chart.indexes) {
+            values[index] = samples.values[name] || samples.timestamps.map(() => null);
+        }
+        chart.line.set(samples.timestamps, ...values);
+    }
+}
+
+document.addEventListener('DOMContentLoaded', function () {
+    // 图表集：图表标题 -> {line: 折线图, indexes: 系列名称 -> 系列索引}
+    let charts = null;
+
+    // 历史采样
+    let history = fetch(`${prefix}/history`)
+        .then(response => response.ok ? response.json() : null)
+        .then(data => {
+            if (data != null && data.samples != null && charts == null) {
+                charts = newCharts(apps, data.metrics, Object.keys(data.samples.values));
+                fillCharts(charts, data.samples);
+            }
+        })
+        .catch(error => console.log('history', error));
 
-                if (ser != null) {
-                    let label = null;
-                    for (let app of apps) {
-                        for (let instance of app.instances) {
-                            if (instance.addr === addr) {
-                                let arr = addr.split(':');
-                                if (arr.length === 2) {
-                                    label = `${app.name}-${arr[1]} ${ser.label}`;
-                                } else {
-                                    label = `${app.name} ${ser.label}`;
-                                }
-                                break;
-                            }
-                        }
-                        if (label != null) {
-                            break;
-                        }
+    history.finally(() => {
+        let eventSource = new EventSource(`${prefix}/event`);
+        eventSource.onmessage = (e) => {
+            let data = JSON.parse(e.data);
+
+            renderBanner(data);
+            if (data.error != null) {
+                // 采集失败，保留原数据
+                return;
+            }
+
+            let apps = data.apps || [];
+            // console.log('apps', apps);
+            for (let app of apps) {
+                for (let instance of app.instances) {
+                    let statusElement = getElement(instance, 'status');
+                    if (statusElement == null) {
+                        // 页面打开后新增的实例
+                        continue;
                     }
-                    ser.label = label;
-                    series.push(ser);
-                    indexes.set(name, index++);
+                    statusElement.textContent = instance.status;
+                    if (instance.status === 'UP') {
+                        statusElement.className = 'status status-ok';
+                    } else if (instance.status === 'DOWN') {
+                        statusElement.className = 'status status-error';
+                    } else {
+                        statusElement.className = 'status status-unknown';
+                    }
+
+                    let timeElement = getElement(instance, 'time');
+                    timeElement.textContent = instance.time;
+
+                    let durationElement = getElement(instance, 'duration');
+                    durationElement.textContent = instance.duration;
                 }
             }
-            // console.log('series', series);
-            line = new Line(document.getElementById('chart'), 'CPU/MEM', 800, 400, series);
-        }
 
-        let timestamp = sample.timestamp;
+            renderAlerts(data.alerts);
 
-        let value = sample.value;
-        let values = Array.from({length: indexes.size}, () => 0);
-        for (let name in value) {
-            let index = indexes.get(name);
-            if (index !== undefined) {
-                values[index] = value[name];
+            let sample = data.sample;
+            // console.log('sample', sample);
+            if (sample == null) {
+                return;
             }
-        }
 
-        line.push(timestamp, ...values);
-    };
-});
+            if (charts == null) {
+                charts = newCharts(apps, data.metrics, Object.keys(sample.value));
+            }
 
+            let timestamp = sample.timestamp;
+
+            let value = sample.value;
+            for (let chart of charts.values()) {
+                let values = Array.from({length: chart.indexes.size}, () => 0);
+                for (let name in value) {
+                    let index = chart.indexes.get(name);
+                    if (index !== undefined) {
+                        values[index] = value[name];
+                    }
+                }
+                chart.line.push(timestamp, ...values);
+            }
+        };
+    });
+});
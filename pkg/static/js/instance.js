@@ -0,0 +1,60 @@
+// @author xiangqian
+// @date 2025/08/03 10:26
+
+/**
+ * 加载实例区间采样并绘制图表
+ * @param form 表单
+ */
+function load(form) {
+    let params = new URLSearchParams(new FormData(form));
+    let errorElement = document.getElementById('error');
+    errorElement.textContent = '';
+    fetch(`${prefix}/instance/data?${params}`)
+        .then(response => {
+            if (!response.ok) {
+                return response.text().then(text => {
+                    throw new Error(text);
+                });
+            }
+            return response.json();
+        })
+        .then(data => {
+            let element = document.getElementById('chart');
+            element.innerHTML = '';
+
+            let samples = data.samples;
+            // 按图表分组系列
+            let groups = new Map();
+            for (let metric of data.metrics) {
+                for (let name in samples.values) {
+                    if (name.split(',')[2] !== metric.name) {
+                        continue;
+                    }
+
+                    let group = groups.get(metric.chart);
+                    if (group === undefined) {
+                        group = {series: new Array(), values: new Array()};
+                        groups.set(metric.chart, group);
+                    }
+                    group.series.push(newSeries(metric));
+                    group.values.push(samples.values[name]);
+                }
+            }
+
+            for (let [title, group] of groups) {
+                let div = document.createElement('div');
+                element.appendChild(div);
+                let line = new Line(div, title, 800, 300, group.series);
+                line.set(samples.timestamps, ...group.values);
+            }
+        })
+        .catch(error => {
+            errorElement.textContent = error.message;
+        });
+}
+
+document.addEventListener('DOMContentLoaded', function () {
+    let form = document.getElementById('form');
+    form.addEventListener('change', () => load(form));
+    load(form);
+});
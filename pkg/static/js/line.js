@@ -103,6 +103,32 @@ const YAxis = {
     Right: 'yRight',
 };
 
+/**
+ * 根据指标创建图表系列
+ * @param metric 指标
+ * @returns {object}
+ */
+function newSeries(metric) {
+    let ser = {
+        label: metric.label || metric.name,
+        scale: metric.axis === 'right' ? YAxis.Right : YAxis.Left,
+    };
+    if (metric.unit === 'percent') {
+        ser.format = (u, v) => v === null ? '--' : v.toFixed(2) + '%';
+        ser.formats = (u, vals) => vals.map(v => v.toFixed(0) + '%');
+    } else if (metric.unit === 'bytes') {
+        ser.format = (u, v) => v === null ? '--' : formatBytes(v, 2);
+        ser.formats = (u, vals) => vals.map(v => formatBytes(v, 0));
+    } else if (metric.unit === 'bytes/s') {
+        ser.format = (u, v) => v === null ? '--' : formatBytes(v, 2) + '/s';
+        ser.formats = (u, vals) => vals.map(v => formatBytes(v, 0) + '/s');
+    } else {
+        ser.format = (u, v) => v === null ? '--' : v.toFixed(2);
+        ser.formats = (u, vals) => vals.map(v => v.toFixed(0));
+    }
+    return ser;
+}
+
 /**
  * uPlot Line -- 折线图
  * https://github.com/leeoniya/uPlot
@@ -249,3 +275,14 @@ Line.prototype.push = function (time, ...values) {
     // 更新图表
     this.chart.setData(data);
 }
+
+/**
+ * 设置数据点（替换已有的数据点）
+ * @param times   时间戳（毫秒数）集
+ * @param values  n 个系列数据集
+ */
+Line.prototype.set = function (times, ...values) {
+    // 只保留最近 maxPoints 个数据点
+    let start = Math.max(0, times.length - this.maxPoints);
+    this.chart.setData([times.slice(start), ...values.map(vals => vals.slice(start))]);
+}
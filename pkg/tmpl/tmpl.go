@@ -9,16 +9,20 @@ import (
 	"io"
 	"log"
 	"strings"
+	text_template "text/template"
 )
 
 // 是否是开发环境
 const dev = false
 
-//go:embed html/*
+//go:embed html/* text/*
 var embedfs embed.FS
 
 var tmpl *template.Template
 
+// 纯文本模板，如：邮件主题和正文
+var textTmpl *text_template.Template
+
 var funcMap = template.FuncMap{
 	"contains":  strings.Contains,
 	"hasPrefix": strings.HasPrefix,
@@ -33,9 +37,34 @@ func Init() error {
 	if err != nil {
 		return err
 	}
+
+	textTmpl, err = text_template.New("").Funcs(text_template.FuncMap(funcMap)).ParseFS(embedfs, "text/*")
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
+// Text 执行纯文本模板，返回执行结果
+func Text(name string, data any) (string, error) {
+	var t = textTmpl
+	if dev {
+		// 从文件系统加载，支持热重载
+		var err error
+		t, err = text_template.New("").Funcs(text_template.FuncMap(funcMap)).ParseGlob("pkg/tmpl/text/*")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var builder strings.Builder
+	err := t.ExecuteTemplate(&builder, fmt.Sprintf("%s.txt", name), data)
+	if err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
 func Execute(w io.Writer, name string, data any) {
 	if dev {
 		// 从文件系统加载，支持热重载
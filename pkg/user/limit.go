@@ -0,0 +1,153 @@
+// @author xiangqian
+// @date 2025/08/28 20:40
+package user
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 登录失败限制：按客户端 IP 和用户名分别记录连续失败次数，
+// 每次失败后需要等待一段时间才能再次尝试，等待时间随失败次数指数增长，
+// 连续失败次数达到上限后锁定一段时间
+
+// 登录失败限制配置
+var limitConfig = LimitConfig{
+	MaxFailures: 5,
+	Delay:       time.Second,
+	MaxDelay:    30 * time.Second,
+	Lockout:     15 * time.Minute,
+}
+
+// 互斥锁
+var limitMutex sync.Mutex
+
+// 失败记录集：ip:<客户端 IP> 或 user:<用户名> -> 失败记录
+var failures = make(map[string]*failure)
+
+// 失败记录数上限，超过时先清理过期的记录，仍然超过时移除最早结束限制的记录，避免大量随机用户名使内存无限增长
+const maxFailures = 10000
+
+// InitLimit 初始化登录失败限制
+func InitLimit(config LimitConfig) {
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+
+	limitConfig = config
+	failures = make(map[string]*failure)
+}
+
+// Wait 客户端 IP 或用户名需要等待多长时间才能再次尝试登录，返回 0 表示允许登录
+func Wait(ip, name string) time.Duration {
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+
+	var now = time.Now()
+	var wait time.Duration
+	for _, key := range limitKeys(ip, name) {
+		if f, ok := failures[key]; ok {
+			wait = max(wait, f.until.Sub(now))
+		}
+	}
+	return wait
+}
+
+// Failed 记录登录失败，返回失败次数（客户端 IP 和用户名中较大者）以及再次尝试前需要等待的时间
+func Failed(ip, name string) (int, time.Duration) {
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+
+	var now = time.Now()
+	if len(failures) >= maxFailures {
+		for key, f := range failures {
+			if f.expired(now) {
+				delete(failures, key)
+			}
+		}
+	}
+
+	var count int
+	var wait time.Duration
+	for _, key := range limitKeys(ip, name) {
+		f, ok := failures[key]
+		if !ok || f.expired(now) {
+			if !ok && len(failures) >= maxFailures {
+				evict()
+			}
+			f = &failure{}
+			failures[key] = f
+		}
+		f.count++
+		f.last = now
+
+		var d time.Duration
+		if limitConfig.MaxFailures > 0 && f.count >= limitConfig.MaxFailures {
+			// 锁定
+			d = limitConfig.Lockout
+		} else if limitConfig.Delay > 0 {
+			// 指数延迟：delay * 2^(count-1)，不超过 max_delay，逐次翻倍并在达到 max_delay 时停止，避免溢出
+			d = limitConfig.Delay
+			for i := 1; i < f.count && d <= math.MaxInt64/2; i++ {
+				if limitConfig.MaxDelay > 0 && d >= limitConfig.MaxDelay {
+					break
+				}
+				d *= 2
+			}
+			if limitConfig.MaxDelay > 0 && d > limitConfig.MaxDelay {
+				d = limitConfig.MaxDelay
+			}
+		}
+		f.until = now.Add(d)
+
+		count = max(count, f.count)
+		wait = max(wait, d)
+	}
+	return count, wait
+}
+
+// Succeeded 登录成功，清除客户端 IP 和用户名的失败记录
+func Succeeded(ip, name string) {
+	limitMutex.Lock()
+	defer limitMutex.Unlock()
+
+	for _, key := range limitKeys(ip, name) {
+		delete(failures, key)
+	}
+}
+
+// 移除最早结束限制的失败记录
+func evict() {
+	var key string
+	var until time.Time
+	for k, f := range failures {
+		if key == "" || f.until.Before(until) {
+			key, until = k, f.until
+		}
+	}
+	delete(failures, key)
+}
+
+func limitKeys(ip, name string) []string {
+	return []string{"ip:" + ip, "user:" + name}
+}
+
+// 失败记录
+type failure struct {
+	count int       // 连续失败次数
+	last  time.Time // 最近一次失败时间
+	until time.Time // 在此时间之前不允许再次尝试
+}
+
+// 失败记录是否已过期：锁定或延迟结束后，超过锁定时长没有再失败，则重新计数
+func (f *failure) expired(now time.Time) bool {
+	return now.After(f.until) && now.Sub(f.last) > max(limitConfig.Lockout, limitConfig.MaxDelay)
+}
+
+// LimitConfig 登录失败限制配置
+type LimitConfig struct {
+	MaxFailures int           // 连续失败次数达到该值后锁定，0 表示不锁定
+	Delay       time.Duration // 首次失败后的等待时间，之后每次失败翻倍，0 表示不等待
+	MaxDelay    time.Duration // 最大等待时间
+	Lockout     time.Duration // 锁定时长
+}
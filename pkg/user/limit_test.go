@@ -0,0 +1,62 @@
+// @author xiangqian
+// @date 2025/08/28 21:25
+package user
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLimit(t *testing.T) {
+	InitLimit(LimitConfig{MaxFailures: 4, Delay: time.Second, MaxDelay: 3 * time.Second, Lockout: time.Hour})
+
+	if wait := Wait("1.1.1.1", "admin"); wait != 0 {
+		t.Fatalf("wait: %s", wait)
+	}
+
+	// 指数延迟：1s、2s、3s（max_delay），第 4 次失败后锁定
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, time.Hour} {
+		count, wait := Failed("1.1.1.1", "admin")
+		if count != i+1 || wait != want {
+			t.Fatalf("failed %d: %d %s, want %s", i+1, count, wait, want)
+		}
+	}
+
+	// 同一用户名从其他 IP 登录，以及同一 IP 登录其他用户名，都需要等待
+	if wait := Wait("2.2.2.2", "admin"); wait <= 59*time.Minute {
+		t.Fatalf("other ip: %s", wait)
+	}
+	if wait := Wait("1.1.1.1", "guest"); wait <= 59*time.Minute {
+		t.Fatalf("other user: %s", wait)
+	}
+	if wait := Wait("2.2.2.2", "guest"); wait != 0 {
+		t.Fatalf("other ip and user: %s", wait)
+	}
+
+	Succeeded("1.1.1.1", "admin")
+	if wait := Wait("1.1.1.1", "admin"); wait != 0 {
+		t.Fatalf("succeeded: %s", wait)
+	}
+}
+
+func TestLimitOverflow(t *testing.T) {
+	// 不锁定时，多次失败后的等待时间不会溢出为负数
+	InitLimit(LimitConfig{MaxFailures: 0, Delay: 10 * time.Second, MaxDelay: time.Minute, Lockout: time.Hour})
+	for i := 0; i < 40; i++ {
+		if _, wait := Failed("1.1.1.1", "admin"); wait <= 0 || wait > time.Minute {
+			t.Fatalf("failed %d: %s", i+1, wait)
+		}
+	}
+
+	// 大量随机用户名时失败记录数不超过上限
+	for i := 0; i < maxFailures+100; i++ {
+		Failed("1.1.1.1", "user"+strconv.Itoa(i))
+	}
+	if len(failures) > maxFailures {
+		t.Fatalf("failures: %d", len(failures))
+	}
+	if wait := Wait("1.1.1.1", ""); wait <= 0 {
+		t.Fatalf("ip evicted: %s", wait)
+	}
+}
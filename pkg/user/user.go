@@ -0,0 +1,188 @@
+// @author xiangqian
+// @date 2025/08/16 10:12
+package user
+
+import (
+	"errors"
+	"fmt"
+	"gmon/pkg/xhttp"
+	"golang.org/x/crypto/bcrypt"
+	pkg_ini "gopkg.in/ini.v1"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 用户文件格式（ini），每个小节为一个用户，小节名称为用户名：
+//   [admin]
+//   passwd = $2a$10$...  # bcrypt 密码哈希，使用 gmon add-user 命令生成
+
+// 读写互斥锁
+var rwMutex sync.RWMutex
+
+// 用户集：用户名 -> 用户
+var users = make(map[string]*User)
+
+// 用户不存在时用于比较的密码哈希，使登录耗时与用户是否存在无关，避免通过响应时间枚举用户名
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("gmon"), bcrypt.DefaultCost)
+
+// Init 加载用户文件，roles 为用户名到角色的映射，未配置角色的用户为只读用户
+func Init(file string, roles map[string]xhttp.Role) error {
+	arr, err := Load(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		// 不提供默认用户，首次运行时需要先添加用户
+		return fmt.Errorf("%s: not found, create the first user with: gmon add-user <name>", file)
+	}
+	if err != nil {
+		return err
+	}
+	if len(arr) == 0 {
+		return fmt.Errorf("%s: no user, add one with: gmon add-user <name>", file)
+	}
+
+	var m = make(map[string]*User, len(arr))
+	for _, user := range arr {
+		user.Role = xhttp.RoleViewer
+		if role, ok := roles[user.Name]; ok {
+			user.Role = role
+		}
+		m[user.Name] = user
+	}
+	// 用户文件中不存在的用户（如已删除的用户）的角色配置被忽略，不影响启动和重新加载
+	for name := range roles {
+		if _, ok := m[name]; !ok {
+			log.Printf("user: [role] %s: user not found in %s, ignored\n", name, file)
+		}
+	}
+
+	rwMutex.Lock()
+	var old = users
+	users = m
+	rwMutex.Unlock()
+
+	// 重新加载时，已删除、降级或者修改密码的用户的会话失效，无需等待会话过期
+	for name, user := range old {
+		var role xhttp.Role
+		if u, ok := m[name]; ok && u.Passwd == user.Passwd {
+			role = u.Role
+		}
+		if role >= user.Role {
+			continue
+		}
+		count, err := xhttp.DelUserSessions(name, role)
+		if err != nil {
+			log.Printf("user: delete sessions of %s: %v\n", name, err)
+		} else if count > 0 {
+			log.Printf("user: %s removed, demoted or password changed, %d session(s) deleted\n", name, count)
+		}
+	}
+	return nil
+}
+
+// Load 读取用户文件
+func Load(file string) ([]*User, error) {
+	f, err := pkg_ini.Load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var arr []*User
+	for _, section := range f.Sections() {
+		if section.Name() == pkg_ini.DefaultSection {
+			continue
+		}
+		var user = &User{
+			Name:   section.Name(),
+			Passwd: strings.TrimSpace(section.Key("passwd").String()),
+		}
+		if _, err = bcrypt.Cost([]byte(user.Passwd)); err != nil {
+			return nil, fmt.Errorf("%s: [%s] passwd: %v", file, user.Name, err)
+		}
+		arr = append(arr, user)
+	}
+	return arr, nil
+}
+
+// Users 用户集，按用户名排序
+func Users() []User {
+	rwMutex.RLock()
+	defer rwMutex.RUnlock()
+
+	var arr = make([]User, 0, len(users))
+	for _, user := range users {
+		arr = append(arr, *user)
+	}
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].Name < arr[j].Name
+	})
+	return arr
+}
+
+// Authenticate 校验用户名和密码，成功时返回用户
+func Authenticate(name, passwd string) (*User, bool) {
+	rwMutex.RLock()
+	user, ok := users[name]
+	rwMutex.RUnlock()
+
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(passwd))
+		return nil, false
+	}
+
+	// bcrypt 以恒定时间比较哈希
+	if bcrypt.CompareHashAndPassword([]byte(user.Passwd), []byte(passwd)) != nil {
+		return nil, false
+	}
+	return user, true
+}
+
+// Add 添加用户到用户文件，用户已存在时更新其密码
+func Add(file, name, passwd string) error {
+	if name == "" || strings.ContainsAny(name, "[]\r\n") {
+		return fmt.Errorf("invalid user name: %q", name)
+	}
+	if passwd == "" {
+		return fmt.Errorf("password is required")
+	}
+
+	hash, err := Hash(passwd)
+	if err != nil {
+		return err
+	}
+
+	// 用户文件不存在时新建
+	f, err := pkg_ini.LooseLoad(file)
+	if err != nil {
+		return err
+	}
+	f.Section(name).Key("passwd").SetValue(hash)
+
+	// 用户文件包含密码哈希，只允许所有者读写
+	w, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// Hash 生成密码哈希
+func Hash(passwd string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwd), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// User 用户
+type User struct {
+	Name   string     // 用户名
+	Passwd string     // 密码哈希（bcrypt）
+	Role   xhttp.Role // 角色
+}
@@ -0,0 +1,111 @@
+// @author xiangqian
+// @date 2025/08/16 10:40
+package user
+
+import (
+	"gmon/pkg/xhttp"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddAuthenticate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.ini")
+	if err := Add(file, "admin", "secret#1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(file, "guest", "guest"); err != nil {
+		t.Fatal(err)
+	}
+	// 已存在的用户更新密码
+	if err := Add(file, "guest", "guest2"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret#1") || strings.Contains(string(data), "guest2") {
+		t.Fatalf("plaintext password in users file:\n%s", data)
+	}
+
+	// 用户文件中不存在的用户的角色配置被忽略
+	if err = Init(file, map[string]xhttp.Role{"admin": xhttp.RoleAdmin, "bob": xhttp.RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	if user, ok := Authenticate("admin", "secret#1"); !ok || user.Name != "admin" || user.Role != xhttp.RoleAdmin {
+		t.Fatalf("admin: %v %v", user, ok)
+	}
+	if _, ok := Authenticate("admin", "secret"); ok {
+		t.Fatal("admin: wrong password accepted")
+	}
+	if _, ok := Authenticate("guest", "guest"); ok {
+		t.Fatal("guest: old password accepted")
+	}
+	if user, ok := Authenticate("guest", "guest2"); !ok || user.Role != xhttp.RoleViewer {
+		t.Fatalf("guest: %v %v", user, ok)
+	}
+	if _, ok := Authenticate("nobody", "secret#1"); ok {
+		t.Fatal("unknown user accepted")
+	}
+}
+
+func TestLoadInvalidHash(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.ini")
+	if err := os.WriteFile(file, []byte("[admin]\npasswd = admin\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(file, nil); err == nil {
+		t.Fatal("plaintext password accepted")
+	}
+}
+
+func TestInitNotFound(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.ini")
+	err := Init(file, nil)
+	if err == nil || !strings.Contains(err.Error(), "gmon add-user") {
+		t.Fatalf("missing users file: %v", err)
+	}
+}
+
+func TestInitSessions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.ini")
+	for _, name := range []string{"admin", "bob", "carol"} {
+		if err := Add(file, name, "secret"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var roles = map[string]xhttp.Role{"admin": xhttp.RoleAdmin, "bob": xhttp.RoleAdmin, "carol": xhttp.RoleAdmin}
+	if err := Init(file, roles); err != nil {
+		t.Fatal(err)
+	}
+	for name := range roles {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if err := xhttp.SetSession(httptest.NewRecorder(), r, name, xhttp.RoleAdmin); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// bob 降级，carol 删除，admin 不变
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(file, []byte(strings.Split(string(data), "[carol]")[0]), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = Init(file, map[string]xhttp.Role{"admin": xhttp.RoleAdmin}); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := xhttp.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].User != "admin" {
+		t.Fatalf("sessions: %+v", sessions)
+	}
+}
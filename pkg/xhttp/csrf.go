@@ -0,0 +1,73 @@
+// @author xiangqian
+// @date 2025/08/29 20:12
+package xhttp
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// CSRF 令牌：登录后的令牌与会话绑定，保存在会话中；
+// 登录前（登录表单）没有会话，使用 Cookie 保存令牌，提交时比较表单和 Cookie 中的令牌。
+// 表单使用 csrf 字段提交令牌，脚本请求使用 X-CSRF-Token 请求头
+
+// 登录表单 CSRF 令牌 Cookie 的有效期（单位：秒）
+const loginCsrfMaxAge = 10 * 60
+
+// Csrf 会话的 CSRF 令牌，会话不存在时返回空字符串
+func Csrf(r *http.Request) string {
+	session, err := GetSession(r)
+	if err != nil || session == nil {
+		return ""
+	}
+	return session.Csrf
+}
+
+// CheckCsrf 校验请求中的 CSRF 令牌是否与会话中的令牌一致
+func CheckCsrf(r *http.Request) bool {
+	return equal(requestCsrf(r), Csrf(r))
+}
+
+// LoginCsrf 生成登录表单的 CSRF 令牌，并保存到 Cookie
+func LoginCsrf(w http.ResponseWriter) (string, error) {
+	token, err := randToken()
+	if err != nil {
+		return "", err
+	}
+	SetCookie(w, "csrf", token, loginCsrfMaxAge)
+	return token, nil
+}
+
+// CheckLoginCsrf 校验登录表单的 CSRF 令牌是否与 Cookie 中的令牌一致，校验后删除 Cookie
+func CheckLoginCsrf(w http.ResponseWriter, r *http.Request) bool {
+	token, err := GetCookie(r, "csrf")
+	if err != nil {
+		return false
+	}
+	SetCookie(w, "csrf", "", -1)
+	return equal(requestCsrf(r), token)
+}
+
+// 请求中的 CSRF 令牌
+func requestCsrf(r *http.Request) string {
+	if token := r.Header.Get("X-CSRF-Token"); token != "" {
+		return token
+	}
+	return r.PostFormValue("csrf")
+}
+
+// 以恒定时间比较令牌，空令牌视为不一致
+func equal(a, b string) bool {
+	return a != "" && b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// 生成 32 字节（256 位）的随机令牌
+func randToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,118 @@
+// @author xiangqian
+// @date 2025/08/27 20:58
+package xhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 会话文件扩展名
+const sessionExt = ".json"
+
+// FileStore 文件会话存储，每个会话保存为目录下的一个 JSON 文件。
+// 重启后会话仍然有效，多个实例挂载同一目录时可以共享会话。
+// 文件名为会话 id 的摘要，避免目录列表泄露会话 id
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore 创建文件会话存储，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	// 会话文件包含会话 id，只允许所有者访问
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (store *FileStore) Get(id string) (*Session, error) {
+	session, err := store.read(store.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return session, err
+}
+
+func (store *FileStore) Set(session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名，避免其他实例读取到不完整的文件
+	tmp, err := os.CreateTemp(store.dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), store.path(session.Id))
+}
+
+func (store *FileStore) Del(id string) error {
+	err := os.Remove(store.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (store *FileStore) List() ([]*Session, error) {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var arr = make([]*Session, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionExt) {
+			continue
+		}
+		session, err := store.read(filepath.Join(store.dir, entry.Name()))
+		if err != nil {
+			// 文件可能已被其他实例删除，或者已损坏，跳过
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("session %s: %v\n", entry.Name(), err)
+			}
+			continue
+		}
+		arr = append(arr, session)
+	}
+	return arr, nil
+}
+
+// 读取会话文件
+func (store *FileStore) read(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// 会话文件路径
+func (store *FileStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(store.dir, hex.EncodeToString(sum[:])+sessionExt)
+}
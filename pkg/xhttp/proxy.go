@@ -0,0 +1,143 @@
+// @author xiangqian
+// @date 2025/09/01 20:05
+package xhttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 反向代理认证：gmon 部署在负责认证的反向代理之后时，信任代理设置的用户请求头，
+// 自动为该用户创建会话，用户无需再次登录。
+// 只信任来自配置的代理网段的请求，防止客户端直接访问 gmon 时伪造请求头
+
+// 反向代理配置
+var proxy ProxyConfig
+
+// InitProxy 初始化反向代理认证
+func InitProxy(config ProxyConfig) {
+	proxy = config
+}
+
+// ParseCidrs 解析网段，单个 IP 视为 /32 或 /128 网段
+func ParseCidrs(arr []string) ([]*net.IPNet, error) {
+	var cidrs []*net.IPNet
+	for _, s := range arr {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip: %q", s)
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// ProxyUser 受信任的反向代理设置的用户，请求不是来自受信任的代理时返回空字符串
+func ProxyUser(r *http.Request) string {
+	if proxy.Header == "" || !trusted(remoteIp(r)) {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(proxy.Header))
+}
+
+// 为反向代理设置的用户设置会话，并将会话 id 设置到请求中，使后续处理可以获取到会话。
+// 复用该用户未过期的代理会话，不保存 Cookie 的客户端（如脚本、健康检查）不会每次请求都创建会话，
+// 避免超过最大会话数后其他用户的会话被清理
+func proxyLogin(w http.ResponseWriter, r *http.Request, user string) error {
+	var role = proxy.Roles[user]
+	if role == 0 {
+		role = RoleViewer
+	}
+
+	session, err := proxySession(user, role)
+	if err != nil {
+		return err
+	}
+	var id string
+	if session != nil {
+		id = session.Id
+		// 删除请求中原有的其他会话
+		if old, err := GetCookie(r, "session_id"); err == nil && old != id {
+			if err = store.Del(old); err != nil {
+				return err
+			}
+		}
+		SetCookie(w, "session_id", id, int(time.Until(session.ExpiresAt).Seconds()))
+	} else {
+		id, err = newSession(w, r, user, role, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 替换请求中原有的会话 Cookie
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != "session_id" {
+			r.AddCookie(c)
+		}
+	}
+	r.AddCookie(&http.Cookie{Name: "session_id", Value: id})
+	return nil
+}
+
+// 用户未过期的代理会话，不存在时返回 nil
+func proxySession(user string, role Role) (*Session, error) {
+	sessionArr, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var now = time.Now()
+	for _, session := range sessionArr {
+		if session.Proxy && session.User == user && session.Role == role && session.ExpiresAt.After(now) {
+			return session, nil
+		}
+	}
+	return nil, nil
+}
+
+// IP 是否属于受信任的代理网段
+func trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range proxy.Cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 直接连接的对端 IP
+func remoteIp(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ProxyConfig 反向代理配置
+type ProxyConfig struct {
+	Header string          // 用户请求头，如 X-Forwarded-User，为空时不启用反向代理认证
+	Cidrs  []*net.IPNet    // 受信任的代理网段
+	Roles  map[string]Role // 用户角色：用户名 -> 角色，未配置角色的用户为只读用户
+}
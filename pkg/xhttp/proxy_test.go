@@ -0,0 +1,94 @@
+// @author xiangqian
+// @date 2025/09/01 21:10
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	cidrs, err := ParseCidrs([]string{"10.0.0.0/8", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitProxy(ProxyConfig{Header: "X-Forwarded-User", Cidrs: cidrs, Roles: map[string]Role{"alice": RoleAdmin}})
+	defer InitProxy(ProxyConfig{})
+
+	var got string
+	Handle("/proxy", "/admin", RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		got = User(r)
+	})
+
+	request := func(remoteAddr, user string) *httptest.ResponseRecorder {
+		got = ""
+		r := httptest.NewRequest(http.MethodGet, "/proxy/admin", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-User", user)
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, r)
+		return w
+	}
+
+	// 受信任的代理
+	w := request("10.1.2.3:5000", "alice")
+	if w.Code != http.StatusOK || got != "alice" {
+		t.Fatalf("trusted: %d %q", w.Code, got)
+	}
+	if len(w.Result().Cookies()) == 0 {
+		t.Fatal("trusted: no session cookie")
+	}
+
+	// 不保存 Cookie 的客户端复用该用户的代理会话，不会每次请求都创建会话
+	before, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if w = request("10.1.2.3:5000", "alice"); w.Code != http.StatusOK || got != "alice" {
+			t.Fatalf("trusted: %d %q", w.Code, got)
+		}
+	}
+	if after, err := store.List(); err != nil || len(after) != len(before) {
+		t.Fatalf("sessions: %d -> %d, %v", len(before), len(after), err)
+	}
+
+	// 未配置角色的用户为只读用户
+	if w = request("127.0.0.1:5000", "bob"); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer: %d", w.Code)
+	}
+
+	// 不受信任的来源伪造请求头
+	if w = request("192.168.1.1:5000", "alice"); w.Code != http.StatusFound || got != "" {
+		t.Fatalf("untrusted: %d %q", w.Code, got)
+	}
+}
+
+func TestClientIp(t *testing.T) {
+	cidrs, err := ParseCidrs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitProxy(ProxyConfig{Cidrs: cidrs})
+	defer InitProxy(ProxyConfig{})
+
+	for _, c := range []struct {
+		remoteAddr string
+		forwarded  string
+		ip         string
+	}{
+		{"1.2.3.4:5000", "5.6.7.8", "1.2.3.4"},
+		{"10.0.0.1:5000", "", "10.0.0.1"},
+		{"10.0.0.1:5000", "9.9.9.9, 5.6.7.8, 10.0.0.2", "5.6.7.8"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if ip := ClientIp(r); ip != c.ip {
+			t.Errorf("%s %s: %s, want %s", c.remoteAddr, c.forwarded, ip, c.ip)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// @author xiangqian
+// @date 2025/08/26 20:14
+package xhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Role 角色，权限由低到高
+type Role byte
+
+const (
+	RoleViewer Role = iota + 1 // 只读用户：仪表盘和事件流
+	RoleAdmin                  // 管理员：配置、会话管理等管理页面
+)
+
+// ParseRole 解析角色名称
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "viewer":
+		return RoleViewer, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return 0, fmt.Errorf("invalid role: %q", name)
+	}
+}
+
+func (role Role) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + role.String() + `"`), nil
+}
+
+func (role *Role) UnmarshalJSON(data []byte) error {
+	var name string
+	err := json.Unmarshal(data, &name)
+	if err != nil {
+		return err
+	}
+	*role, err = ParseRole(name)
+	return err
+}
+
+func (role Role) String() string {
+	switch role {
+	case RoleViewer:
+		return "viewer"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
@@ -4,7 +4,10 @@ package xhttp
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"net/url"
 	"sort"
@@ -12,14 +15,53 @@ import (
 	"time"
 )
 
-// 读写互斥锁
-var rwMutex sync.RWMutex
+// 会话存储
+var store Store = NewMemoryStore()
 
-// 会话集
-var sessions map[string]*Session
+// 会话有效期
+var maxAge = 12 * time.Hour
 
-func init() {
-	sessions = make(map[string]*Session)
+// 最大会话数
+var maxCount = 100
+
+// Cookie 属性
+var cookie = http.Cookie{Path: "/", SameSite: http.SameSiteLaxMode}
+
+// 互斥锁，保证清理会话时不会并发执行
+var mutex sync.Mutex
+
+// Init 初始化会话存储
+func Init(config Config) error {
+	switch config.Store {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "file":
+		fileStore, err := NewFileStore(config.Dir)
+		if err != nil {
+			return err
+		}
+		store = fileStore
+	default:
+		return fmt.Errorf("invalid session store: %q", config.Store)
+	}
+
+	if config.MaxAge > 0 {
+		maxAge = config.MaxAge
+	}
+	if config.MaxCount > 0 {
+		maxCount = config.MaxCount
+	}
+
+	cookie.Path = config.CookiePath
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	cookie.Secure = config.CookieSecure
+	cookie.SameSite = config.CookieSameSite
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return nil
 }
 
 // Expired 会话是否已过期
@@ -32,11 +74,6 @@ func Expired(r *http.Request) bool {
 
 // GetSession 获取会话
 func GetSession(r *http.Request) (*Session, error) {
-	// 获取读锁，允许多个读操作同时进行
-	rwMutex.RLock()
-	// 释放读锁
-	defer rwMutex.RUnlock()
-
 	// 获取会话 id
 	id, err := GetCookie(r, "session_id")
 	if err != nil {
@@ -44,92 +81,173 @@ func GetSession(r *http.Request) (*Session, error) {
 	}
 
 	// 获取会话
-	session, ok := sessions[id]
-	if !ok {
-		return nil, nil
+	return store.Get(id)
+}
+
+// User 获取会话的登录用户
+func User(r *http.Request) string {
+	session, err := GetSession(r)
+	if err != nil || session == nil {
+		return ""
+	}
+	return session.User
+}
+
+// GetRole 获取会话的角色，会话不存在时返回 0
+func GetRole(r *http.Request) Role {
+	session, err := GetSession(r)
+	if err != nil || session == nil {
+		return 0
 	}
-	return session, nil
+	return session.Role
 }
 
-// SetSession 设置会话
-func SetSession(w http.ResponseWriter) error {
-	// 获取写锁，阻塞其他所有读写操作
-	rwMutex.Lock()
-	// 释放写锁
-	defer rwMutex.Unlock()
+// SetSession 为登录用户设置会话。
+// 每次登录都生成新的会话 id，并删除请求中原有的会话，防止会话固定攻击
+func SetSession(w http.ResponseWriter, r *http.Request, user string, role Role) error {
+	_, err := newSession(w, r, user, role, false)
+	return err
+}
+
+// 创建会话，返回会话 id，proxy 表示由反向代理认证创建的会话
+func newSession(w http.ResponseWriter, r *http.Request, user string, role Role, proxy bool) (string, error) {
+	if id, err := GetCookie(r, "session_id"); err == nil {
+		err = store.Del(id)
+		if err != nil {
+			return "", err
+		}
+	}
 
 	// 生成会话id
 	// 生成 16 字节（128 位）的随机数
 	buf := make([]byte, 16)
 	if _, err := rand.Read(buf); err != nil {
-		return err
+		return "", err
 	}
 	id := base64.RawURLEncoding.EncodeToString(buf)
 
+	// 生成 CSRF 令牌
+	csrf, err := randToken()
+	if err != nil {
+		return "", err
+	}
+
 	// 设置会话
-	var maxAge = 12 * 60 * 60 // 设置会话过期时间为 12 个小时
 	session := &Session{
 		Id:        id,
-		ExpiresAt: time.Now().Add(time.Duration(maxAge) * time.Second),
+		User:      user,
+		Role:      role,
+		Csrf:      csrf,
+		Proxy:     proxy,
+		ExpiresAt: time.Now().Add(maxAge),
+	}
+	err = store.Set(session)
+	if err != nil {
+		return "", err
 	}
-	sessions[id] = session
 
 	// 设置 Cookie
-	SetCookie(w, "session_id", id, maxAge)
+	SetCookie(w, "session_id", id, int(maxAge.Seconds()))
 
 	// 限制最大登录数
-	const maxLen = 100
-	var sLen = len(sessions)
-	if sLen > maxLen {
-		// 会话根据过期时间升序排序
-		var sessionArr = make([]*Session, 0, sLen)
-		for _, session = range sessions {
-			sessionArr = append(sessionArr, session)
-		}
-		sort.Slice(sessionArr, func(i, j int) bool {
-			return sessionArr[i].ExpiresAt.Before(sessionArr[j].ExpiresAt)
-		})
-
-		// 移除超过限制最大登录数的会话
-		var i = 0
-		for l := sLen - maxLen; i < l; i++ {
-			session = sessionArr[i]
-			delete(sessions, session.Id)
+	return id, clean()
+}
+
+// 移除过期的会话，以及超过最大登录数的最早过期的会话
+func clean() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	sessionArr, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	// 会话根据过期时间升序排序
+	sort.Slice(sessionArr, func(i, j int) bool {
+		return sessionArr[i].ExpiresAt.Before(sessionArr[j].ExpiresAt)
+	})
+
+	var now = time.Now()
+	for i, session := range sessionArr {
+		if len(sessionArr)-i <= maxCount && session.ExpiresAt.After(now) {
+			break
 		}
-		// 移除过期的会话
-		for l := sLen; i < l; i++ {
-			session = sessionArr[i]
-			if session.ExpiresAt.Before(time.Now()) {
-				delete(sessions, session.Id)
-			} else {
-				break
-			}
+		err = store.Del(session.Id)
+		if err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
 // DelSession 删除会话
 func DelSession(w http.ResponseWriter, r *http.Request) error {
-	// 获取写锁，阻塞其他所有读写操作
-	rwMutex.Lock()
-	// 释放写锁
-	defer rwMutex.Unlock()
-
 	// 获取会话 id
 	id, err := GetCookie(r, "session_id")
 	if err != nil {
 		return err
 	}
 
-	// 删除会话
-	delete(sessions, id)
-
 	// 设置 Cookie
 	SetCookie(w, "session_id", "", -1)
 
-	return nil
+	// 删除会话
+	return store.Del(id)
+}
+
+// Sessions 未过期的会话集，按过期时间降序排序
+func Sessions() ([]Session, error) {
+	sessionArr, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var now = time.Now()
+	var arr = make([]Session, 0, len(sessionArr))
+	for _, session := range sessionArr {
+		if session.ExpiresAt.After(now) {
+			arr = append(arr, *session)
+		}
+	}
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].ExpiresAt.After(arr[j].ExpiresAt)
+	})
+	return arr, nil
+}
+
+// DelSessionByKey 根据会话标识删除会话，用于管理员强制下线
+func DelSessionByKey(key string) (bool, error) {
+	sessionArr, err := store.List()
+	if err != nil {
+		return false, err
+	}
+
+	for _, session := range sessionArr {
+		if session.Key() == key {
+			return true, store.Del(session.Id)
+		}
+	}
+	return false, nil
+}
+
+// DelUserSessions 删除用户角色高于 role 的会话，用户被删除或降级后使已登录的会话失效，role 为 0 时删除用户的所有会话，返回删除的会话数
+func DelUserSessions(user string, role Role) (int, error) {
+	sessionArr, err := store.List()
+	if err != nil {
+		return 0, err
+	}
+
+	var count = 0
+	for _, session := range sessionArr {
+		if session.User == user && session.Role > role {
+			if err = store.Del(session.Id); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
 }
 
 // GetCookie 获取 Cookie
@@ -151,14 +269,38 @@ func SetCookie(w http.ResponseWriter, name, value string, maxAge int) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     name,                   // Cookie 名称
 		Value:    url.QueryEscape(value), // Cookie 值
-		Path:     "/",                    // Cookie 有效路径，"/" 表示对整个网站有效
+		Path:     cookie.Path,            // Cookie 有效路径，与 HTTP 请求前缀一致
 		HttpOnly: true,                   // 设置为 true 防止 JavaScript 通过 document.cookie 访问，增强安全性
-		MaxAge:   maxAge,                 // Cookie 有效期（单位：秒），设置为正数表示多少秒后过期，设置为 0 表示立即删除 Cookie，设置为负数表示会话 Cookie（浏览器关闭后删除）
+		Secure:   cookie.Secure,          // 设置为 true 时只通过 HTTPS 发送 Cookie
+		SameSite: cookie.SameSite,        // 限制跨站请求携带 Cookie
+		MaxAge:   maxAge,                 // Cookie 有效期（单位：秒），设置为正数表示多少秒后过期，设置为 0 表示会话 Cookie（浏览器关闭后删除），设置为负数表示立即删除 Cookie
 	})
 }
 
 // Session 会话
 type Session struct {
-	Id        string
-	ExpiresAt time.Time
+	Id        string    `json:"id"`
+	User      string    `json:"user"`      // 登录用户
+	Role      Role      `json:"role"`      // 角色
+	Csrf      string    `json:"csrf"`      // CSRF 令牌
+	Proxy     bool      `json:"proxy"`     // 是否由反向代理认证创建
+	ExpiresAt time.Time `json:"expiresAt"` // 过期时间
+}
+
+// Config 会话配置
+type Config struct {
+	Store    string        // 会话存储：memory（内存，默认）、file（文件）
+	Dir      string        // 会话文件目录（file）
+	MaxAge   time.Duration // 会话有效期
+	MaxCount int           // 最大会话数
+
+	CookiePath     string        // Cookie 有效路径，为空时为 /
+	CookieSecure   bool          // Cookie 是否只通过 HTTPS 发送
+	CookieSameSite http.SameSite // Cookie SameSite 属性
+}
+
+// Key 会话标识，会话 id 的摘要，用于在管理页面中展示和删除会话，避免泄露会话 id
+func (session Session) Key() string {
+	sum := sha256.Sum256([]byte(session.Id))
+	return hex.EncodeToString(sum[:8])
 }
@@ -0,0 +1,75 @@
+// @author xiangqian
+// @date 2025/08/27 20:31
+package xhttp
+
+import (
+	"sync"
+)
+
+// Store 会话存储
+type Store interface {
+	// Get 获取会话，会话不存在时返回 nil
+	Get(id string) (*Session, error)
+
+	// Set 保存会话
+	Set(session *Session) error
+
+	// Del 删除会话
+	Del(id string) error
+
+	// List 所有会话（包括已过期的会话）
+	List() ([]*Session, error)
+}
+
+// MemoryStore 内存会话存储，重启后会话丢失，且不能在多个实例之间共享
+type MemoryStore struct {
+	rwMutex  sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore 创建内存会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*Session)}
+}
+
+func (store *MemoryStore) Get(id string) (*Session, error) {
+	store.rwMutex.RLock()
+	defer store.rwMutex.RUnlock()
+
+	session, ok := store.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	// 复制一份，避免并发读写
+	var s = *session
+	return &s, nil
+}
+
+func (store *MemoryStore) Set(session *Session) error {
+	store.rwMutex.Lock()
+	defer store.rwMutex.Unlock()
+
+	var s = *session
+	store.sessions[session.Id] = &s
+	return nil
+}
+
+func (store *MemoryStore) Del(id string) error {
+	store.rwMutex.Lock()
+	defer store.rwMutex.Unlock()
+
+	delete(store.sessions, id)
+	return nil
+}
+
+func (store *MemoryStore) List() ([]*Session, error) {
+	store.rwMutex.RLock()
+	defer store.rwMutex.RUnlock()
+
+	var arr = make([]*Session, 0, len(store.sessions))
+	for _, session := range store.sessions {
+		var s = *session
+		arr = append(arr, &s)
+	}
+	return arr, nil
+}
@@ -0,0 +1,89 @@
+// @author xiangqian
+// @date 2025/08/27 21:30
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range map[string]Store{"memory": NewMemoryStore(), "file": fileStore} {
+		var expiresAt = time.Now().Add(time.Hour).Round(0)
+		err = s.Set(&Session{Id: "a/b+c", User: "admin", Role: RoleAdmin, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		session, err := s.Get("a/b+c")
+		if err != nil || session == nil {
+			t.Fatalf("%s: get: %v %v", name, session, err)
+		}
+		if session.User != "admin" || session.Role != RoleAdmin || !session.ExpiresAt.Equal(expiresAt) {
+			t.Fatalf("%s: get: %+v", name, session)
+		}
+
+		if session, err = s.Get("none"); err != nil || session != nil {
+			t.Fatalf("%s: get none: %v %v", name, session, err)
+		}
+
+		arr, err := s.List()
+		if err != nil || len(arr) != 1 {
+			t.Fatalf("%s: list: %v %v", name, arr, err)
+		}
+
+		if err = s.Del("a/b+c"); err != nil {
+			t.Fatalf("%s: del: %v", name, err)
+		}
+		if session, err = s.Get("a/b+c"); err != nil || session != nil {
+			t.Fatalf("%s: get deleted: %v %v", name, session, err)
+		}
+	}
+}
+
+// 文件存储的会话在重新创建存储（重启）后仍然有效，超过最大会话数时移除最早过期的会话
+func TestFileStoreClean(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(Config{Store: "file", Dir: dir, MaxAge: time.Hour, MaxCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	defer Init(Config{})
+
+	var expired = &Session{Id: "expired", Role: RoleViewer, ExpiresAt: time.Now().Add(-time.Minute)}
+	var oldest = &Session{Id: "oldest", Role: RoleViewer, ExpiresAt: time.Now().Add(time.Minute)}
+	store.Set(expired)
+	store.Set(oldest)
+	store.Set(&Session{Id: "newer", Role: RoleViewer, ExpiresAt: time.Now().Add(2 * time.Minute)})
+	if err := SetSession(nopWriter{}, httptest.NewRequest(http.MethodPost, "/login1", nil), "admin", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新创建存储
+	if err := Init(Config{Store: "file", Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	arr, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids = make(map[string]bool)
+	for _, session := range arr {
+		ids[session.Id] = true
+	}
+	if len(arr) != 2 || ids["expired"] || ids["oldest"] || !ids["newer"] {
+		t.Fatalf("sessions: %v", ids)
+	}
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Header() http.Header         { return http.Header{} }
+func (nopWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (nopWriter) WriteHeader(int)             {}
@@ -0,0 +1,159 @@
+// @author xiangqian
+// @date 2025/09/03 19:40
+package xhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTPS：证书文件修改后（如 certbot 续期）在下次握手时自动重新加载，无需重启服务。
+// 配置客户端 CA 时启用双向认证（mTLS），只接受由该 CA 签发证书的客户端
+
+// 证书文件检查间隔，避免每次握手都读取文件状态
+const certCheckInterval = 5 * time.Second
+
+// NewTlsConfig 根据配置创建 TLS 配置，未配置证书时返回 nil
+func NewTlsConfig(config TlsConfig) (*tls.Config, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	reloader := &certReloader{certFile: config.Cert, keyFile: config.Key}
+	err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig = &tls.Config{
+		MinVersion:     config.MinVersion,
+		GetCertificate: reloader.getCertificate,
+	}
+	if config.ClientCa != "" {
+		data, err := os.ReadFile(config.ClientCa)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no certificate found", config.ClientCa)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// ParseTlsVersion 解析 TLS 版本：1.2、1.3，不支持已废弃的 1.0、1.1（RFC 8996）
+func ParseTlsVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0", "1.1":
+		return 0, fmt.Errorf("tls version %s is deprecated, use 1.2 or 1.3", s)
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid tls version: %q", s)
+}
+
+// RedirectHttps 将 HTTP 请求重定向到 HTTPS 端口
+func RedirectHttps(port uint16) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(int(port)))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// 证书重新加载器
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex     sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time // 证书和私钥文件的最后修改时间
+	checkedAt time.Time // 上次检查文件的时间
+}
+
+func (reloader *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+
+	if time.Since(reloader.checkedAt) >= certCheckInterval {
+		reloader.checkedAt = time.Now()
+		modTime, err := reloader.modifiedAt()
+		if err == nil && !modTime.Equal(reloader.modTime) {
+			// 重新加载失败（如证书和私钥只更新了其中一个）时继续使用原证书
+			if err = reloader.load(); err != nil {
+				log.Printf("reload certificate: %v\n", err)
+			} else {
+				log.Printf("reload certificate: %s\n", reloader.certFile)
+			}
+		}
+	}
+	return reloader.cert, nil
+}
+
+// 加载证书
+func (reloader *certReloader) load() error {
+	modTime, err := reloader.modifiedAt()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return err
+	}
+	reloader.cert = &cert
+	reloader.modTime = modTime
+	reloader.checkedAt = time.Now()
+	return nil
+}
+
+// 证书和私钥文件的最后修改时间
+func (reloader *certReloader) modifiedAt() (time.Time, error) {
+	var modTime time.Time
+	for _, name := range []string{reloader.certFile, reloader.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if modTime.IsZero() {
+		return time.Time{}, errors.New("invalid certificate modification time")
+	}
+	return modTime, nil
+}
+
+// TlsConfig HTTPS 配置
+type TlsConfig struct {
+	Cert         string // 证书文件（PEM），为空时不启用 HTTPS
+	Key          string // 私钥文件（PEM）
+	MinVersion   uint16 // 最低 TLS 版本
+	ClientCa     string // 客户端 CA 证书文件（PEM），不为空时启用双向认证
+	RedirectPort uint16 // HTTP 重定向端口，不为 0 时在该端口监听 HTTP 请求并重定向到 HTTPS
+}
+
+// Enabled 是否启用 HTTPS
+func (config TlsConfig) Enabled() bool {
+	return config.Cert != ""
+}
@@ -0,0 +1,131 @@
+// @author xiangqian
+// @date 2025/09/03 21:05
+package xhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 生成自签名证书，写入证书和私钥文件
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTlsReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "a.example")
+
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	err := reloader.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := func() string {
+		cert, err := reloader.getCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if name := subject(); name != "a.example" {
+		t.Fatalf("subject: %s", name)
+	}
+
+	// 更新证书，检查间隔内不重新加载
+	writeCert(t, certFile, keyFile, "b.example")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if name := subject(); name != "a.example" {
+		t.Fatalf("reloaded within check interval: %s", name)
+	}
+	reloader.checkedAt = time.Time{}
+	if name := subject(); name != "b.example" {
+		t.Fatalf("not reloaded: %s", name)
+	}
+
+	// 证书文件损坏时继续使用原证书
+	os.WriteFile(certFile, []byte("invalid"), 0600)
+	later = later.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	reloader.checkedAt = time.Time{}
+	if name := subject(); name != "b.example" {
+		t.Fatalf("invalid certificate: %s", name)
+	}
+}
+
+func TestRedirectHttps(t *testing.T) {
+	for _, c := range []struct {
+		port     uint16
+		host     string
+		location string
+	}{
+		{443, "example.com:80", "https://example.com/gmon/instance?id=1"},
+		{8443, "example.com", "https://example.com:8443/gmon/instance?id=1"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/gmon/instance?id=1", nil)
+		r.Host = c.host
+		w := httptest.NewRecorder()
+		RedirectHttps(c.port).ServeHTTP(w, r)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != c.location {
+			t.Fatalf("%d %s: %d %s", c.port, c.host, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestParseTlsVersion(t *testing.T) {
+	for s, version := range map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		if v, err := ParseTlsVersion(s); err != nil || v != version {
+			t.Fatalf("%s: %d, %v", s, v, err)
+		}
+	}
+	for _, s := range []string{"1.0", "1.1", "2.0", ""} {
+		if _, err := ParseTlsVersion(s); err == nil {
+			t.Fatalf("%s: accepted", s)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// @author xiangqian
+// @date 2025/08/31 10:18
+package xhttp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API 令牌：供脚本和其他系统通过 Authorization: Bearer <令牌> 请求头访问 JSON 接口和事件流。
+// 令牌由管理员创建和吊销，文件中只保存令牌的 SHA-256 摘要，令牌明文只在创建时展示一次
+
+// 令牌前缀，便于识别和密钥扫描
+const tokenPrefix = "gmon_"
+
+// 令牌范围
+const (
+	ScopeRead  = "read"  // 只读：仪表盘数据和事件流
+	ScopeAdmin = "admin" // 管理：管理接口
+)
+
+// 令牌文件，为空时不启用 API 令牌
+var tokenFile string
+
+// 互斥锁
+var tokenMutex sync.Mutex
+
+// 令牌集缓存，令牌文件修改时重新加载，多个实例共享同一文件时可以共享令牌
+var tokenCache struct {
+	modTime time.Time
+	tokens  []*Token
+}
+
+// InitTokens 初始化 API 令牌文件
+func InitTokens(file string) error {
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	tokenFile = file
+	tokenCache.modTime = time.Time{}
+	tokenCache.tokens = nil
+	if tokenFile == "" {
+		return nil
+	}
+	_, err := loadTokens()
+	return err
+}
+
+// TokenEnabled 是否启用 API 令牌
+func TokenEnabled() bool {
+	return tokenFile != ""
+}
+
+// Tokens 令牌集，按创建时间降序排序
+func Tokens() ([]Token, error) {
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return nil, err
+	}
+	var arr = make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		arr = append(arr, *token)
+	}
+	sort.Slice(arr, func(i, j int) bool {
+		return arr[i].CreatedAt.After(arr[j].CreatedAt)
+	})
+	return arr, nil
+}
+
+// CreateToken 创建令牌，返回令牌明文
+func CreateToken(name string, scopes []string, expiresAt time.Time) (string, error) {
+	if tokenFile == "" {
+		return "", errors.New("api token is disabled")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("token scope is required")
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeAdmin {
+			return "", fmt.Errorf("invalid token scope: %q", scope)
+		}
+	}
+
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return "", err
+	}
+
+	raw := tokenPrefix + rand.Text()
+	var token = &Token{
+		Id:        rand.Text()[:8],
+		Name:      name,
+		Hash:      hashToken(raw),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	err = saveTokens(append(tokens, token))
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// RevokeToken 吊销令牌
+func RevokeToken(id string) (bool, error) {
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return false, err
+	}
+	var arr = slices.DeleteFunc(slices.Clone(tokens), func(token *Token) bool {
+		return token.Id == id
+	})
+	if len(arr) == len(tokens) {
+		return false, nil
+	}
+	return true, saveTokens(arr)
+}
+
+// 校验请求中的令牌，返回令牌；请求中没有 API 令牌或者未启用 API 令牌时返回 nil。
+// 其他认证方式（如前置反向代理使用的 Basic 认证、转发的其他 Bearer 令牌）不是 API 令牌，交由会话和反向代理认证处理
+func requestToken(r *http.Request) (*Token, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	raw = strings.TrimSpace(raw)
+	if !ok || !strings.HasPrefix(raw, tokenPrefix) {
+		return nil, nil
+	}
+
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+
+	if tokenFile == "" {
+		return nil, nil
+	}
+
+	tokens, err := loadTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashToken(raw)
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hash)) == 1 {
+			if token.Expired() {
+				return nil, errors.New("token expired")
+			}
+			var t = *token
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid token")
+}
+
+// 加载令牌文件，文件未修改时使用缓存
+func loadTokens() ([]*Token, error) {
+	info, err := os.Stat(tokenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		tokenCache.modTime = time.Time{}
+		tokenCache.tokens = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().Equal(tokenCache.modTime) {
+		return tokenCache.tokens, nil
+	}
+
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []*Token
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", tokenFile, err)
+	}
+	tokenCache.modTime = info.ModTime()
+	tokenCache.tokens = tokens
+	return tokens, nil
+}
+
+// 保存令牌文件，先写入临时文件再重命名
+func saveTokens(tokens []*Token) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(tokenFile), filepath.Base(tokenFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), tokenFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// 强制下次重新加载
+	tokenCache.modTime = time.Time{}
+	return nil
+}
+
+// 令牌摘要，令牌为高熵随机串，使用 SHA-256 即可
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Token API 令牌
+type Token struct {
+	Id        string    `json:"id"`        // 令牌 ID
+	Name      string    `json:"name"`      // 名称（用途）
+	Hash      string    `json:"hash"`      // 令牌的 SHA-256 摘要
+	Scopes    []string  `json:"scopes"`    // 范围：read、admin
+	CreatedAt time.Time `json:"createdAt"` // 创建时间
+	ExpiresAt time.Time `json:"expiresAt"` // 过期时间，零值表示永不过期
+}
+
+// Role 令牌范围对应的角色
+func (token Token) Role() Role {
+	if slices.Contains(token.Scopes, ScopeAdmin) {
+		return RoleAdmin
+	}
+	if slices.Contains(token.Scopes, ScopeRead) {
+		return RoleViewer
+	}
+	return 0
+}
+
+// Expired 令牌是否已过期
+func (token Token) Expired() bool {
+	return !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(time.Now())
+}
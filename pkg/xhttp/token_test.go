@@ -0,0 +1,94 @@
+// @author xiangqian
+// @date 2025/08/31 11:20
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	if err := InitTokens(file); err != nil {
+		t.Fatal(err)
+	}
+	defer InitTokens("")
+
+	Handle("/token", "/read", RoleViewer, func(w http.ResponseWriter, r *http.Request) {})
+	Handle("/token", "/admin", RoleAdmin, func(w http.ResponseWriter, r *http.Request) {})
+
+	read, err := CreateToken("grafana", []string{ScopeRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := CreateToken("ops", []string{ScopeAdmin}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := CreateToken("old", []string{ScopeAdmin}, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 文件中只保存令牌摘要
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), read) || strings.Contains(string(data), admin) {
+		t.Fatalf("plaintext token in file:\n%s", data)
+	}
+
+	check := func(token, path string, code int) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, r)
+		if w.Code != code {
+			t.Errorf("%s %s: %d, want %d", token, path, w.Code, code)
+		}
+	}
+	check(read, "/token/read", http.StatusOK)
+	check(read, "/token/admin", http.StatusForbidden)
+	check(admin, "/token/admin", http.StatusOK)
+	check(expired, "/token/read", http.StatusUnauthorized)
+	check("gmon_invalid", "/token/read", http.StatusUnauthorized)
+
+	// 其他认证方式（如反向代理的 Basic 认证）不是 API 令牌，按会话认证处理：没有会话时重定向到登录页面
+	r := httptest.NewRequest(http.MethodGet, "/token/read", nil)
+	r.SetBasicAuth("proxy", "secret")
+	w := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, r)
+	if w.Code != http.StatusFound && w.Code != http.StatusSeeOther {
+		t.Errorf("basic auth: %d, want redirect to login", w.Code)
+	}
+
+	// 反向代理转发的其他 Bearer 令牌同样按会话认证处理
+	check("eyJhbGciOiJSUzI1NiJ9.e30.sig", "/token/read", http.StatusFound)
+
+	// 吊销
+	tokens, err := Tokens()
+	if err != nil || len(tokens) != 3 {
+		t.Fatalf("tokens: %v %v", tokens, err)
+	}
+	for _, token := range tokens {
+		if token.Name == "grafana" {
+			if ok, err := RevokeToken(token.Id); !ok || err != nil {
+				t.Fatalf("revoke: %v %v", ok, err)
+			}
+		}
+	}
+	check(read, "/token/read", http.StatusUnauthorized)
+
+	// 未启用 API 令牌时按会话认证处理
+	if err = InitTokens(""); err != nil {
+		t.Fatal(err)
+	}
+	check(admin, "/token/read", http.StatusFound)
+}
@@ -4,11 +4,42 @@ package xhttp
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func Handle(prefix, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+// Handle 注册需要登录的路由，role 为访问该路由所需的最低角色
+func Handle(prefix, pattern string, role Role, handler func(http.ResponseWriter, *http.Request)) {
 	http.HandleFunc(fmt.Sprintf("%s%s", prefix, pattern), func(w http.ResponseWriter, r *http.Request) {
+		// API 令牌，不使用 Cookie，无需校验 CSRF 令牌
+		token, err := requestToken(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if token != nil {
+			if token.Role() < role {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			handler(w, r)
+			return
+		}
+
+		// 受信任的反向代理设置了用户时，没有会话或者会话用户与代理设置的用户不一致，则为该用户创建会话
+		if user := ProxyUser(r); user != "" {
+			if session, err := GetSession(r); err != nil || session == nil || session.User != user || session.ExpiresAt.Before(time.Now()) {
+				err = proxyLogin(w, r, user)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
 		// 会话是否已过期
 		if Expired(r) {
 			// 重定向到登录页
@@ -16,7 +47,48 @@ func Handle(prefix, pattern string, handler func(http.ResponseWriter, *http.Requ
 			return
 		}
 
+		// 角色权限不足
+		if GetRole(r) < role {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		// 修改状态的请求校验 CSRF 令牌
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !CheckCsrf(r) {
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+		}
+
 		// 会话有效，继续处理请求
 		handler(w, r)
 	})
 }
+
+// ClientIp 客户端 IP。
+// 请求来自受信任的反向代理时，从 X-Forwarded-For 中由右向左取第一个不受信任的 IP
+func ClientIp(r *http.Request) string {
+	ip := remoteIp(r)
+	if ip == nil {
+		return r.RemoteAddr
+	}
+	if !trusted(ip) {
+		return ip.String()
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		fip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if fip == nil {
+			break
+		}
+		ip = fip
+		if !trusted(ip) {
+			break
+		}
+	}
+	return ip.String()
+}
@@ -0,0 +1,97 @@
+// @author xiangqian
+// @date 2025/08/26 21:40
+package xhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandleRole(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	Handle("/role", "/viewer", RoleViewer, ok)
+	Handle("/role", "/admin", RoleAdmin, ok)
+
+	// 登录并获取会话 Cookie
+	login := func(role Role) *http.Cookie {
+		w := httptest.NewRecorder()
+		if err := SetSession(w, httptest.NewRequest(http.MethodPost, "/login1", nil), role.String(), role); err != nil {
+			t.Fatal(err)
+		}
+		return w.Result().Cookies()[0]
+	}
+	viewer, admin := login(RoleViewer), login(RoleAdmin)
+
+	for _, c := range []struct {
+		cookie *http.Cookie
+		path   string
+		code   int
+	}{
+		{nil, "/role/viewer", http.StatusFound},
+		{viewer, "/role/viewer", http.StatusOK},
+		{viewer, "/role/admin", http.StatusForbidden},
+		{admin, "/role/viewer", http.StatusOK},
+		{admin, "/role/admin", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.cookie != nil {
+			r.AddCookie(c.cookie)
+		}
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%v %s: %d, want %d", c.cookie, c.path, w.Code, c.code)
+		}
+	}
+}
+
+func TestHandleCsrf(t *testing.T) {
+	Handle("/csrf", "/post", RoleViewer, func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	if err := SetSession(w, httptest.NewRequest(http.MethodPost, "/login1", nil), "admin", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	cookie := w.Result().Cookies()[0]
+	session, err := store.Get(cookie.Value)
+	if err != nil || session == nil {
+		t.Fatalf("session: %v %v", session, err)
+	}
+
+	for _, c := range []struct {
+		method string
+		token  string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, "", http.StatusForbidden},
+		{http.MethodPost, "invalid", http.StatusForbidden},
+		{http.MethodPost, session.Csrf, http.StatusOK},
+	} {
+		r := httptest.NewRequest(c.method, "/csrf/post", strings.NewReader(url.Values{"csrf": {c.token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, r)
+		if w.Code != c.code {
+			t.Errorf("%s %q: %d, want %d", c.method, c.token, w.Code, c.code)
+		}
+	}
+
+	// 再次登录时生成新的会话 id，原有的会话失效
+	r := httptest.NewRequest(http.MethodPost, "/login1", nil)
+	r.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	if err = SetSession(w, r, "admin", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if id := w.Result().Cookies()[0].Value; id == cookie.Value {
+		t.Fatal("session id not rotated")
+	}
+	if session, err = store.Get(cookie.Value); err != nil || session != nil {
+		t.Fatalf("old session: %v %v", session, err)
+	}
+}
@@ -0,0 +1,152 @@
+// @author xiangqian
+// @date 2025/09/04 20:12
+package main
+
+import (
+	"context"
+	"fmt"
+	"gmon/pkg/alert"
+	"gmon/pkg/notify"
+	"gmon/pkg/prom"
+	"gmon/pkg/user"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// 配置热加载：配置文件、指标目录文件或用户文件修改后，或收到 SIGHUP 信号时，重新加载配置。
+// 新配置校验失败时记录日志并继续使用原配置；已删除、降级或者修改密码的用户的会话立即失效。
+// 可以在运行时生效的配置：Prometheus 数据源（地址、认证、TLS 等）、指标目录、用户和角色、登录失败限制、告警规则、通知；
+// 其他配置（端口、请求前缀、HTTPS、超时时间、会话、单点登录、反向代理认证、API 令牌）需要重启后生效
+
+// 文件检查间隔
+const watchInterval = 2 * time.Second
+
+// 监视配置文件并在修改后重新加载，直到 ctx 被取消
+func watch(ctx context.Context, name string, config Config) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var modTimes = fileModTimes(name, config)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("reload config: SIGHUP\n")
+		case <-ticker.C:
+			arr := fileModTimes(name, config)
+			if reflect.DeepEqual(arr, modTimes) {
+				continue
+			}
+			log.Printf("reload config: file changed\n")
+		}
+
+		newConfig, err := reload(name, config)
+		// 无论是否加载成功，都记录当前的修改时间，避免对同一错误重复记录日志
+		modTimes = fileModTimes(name, newConfig)
+		if err != nil {
+			log.Printf("reload config: %v, keep the current config\n", err)
+			continue
+		}
+		config = newConfig
+		log.Printf("reload config: ok\n")
+	}
+}
+
+// 重新加载配置，先校验新配置再应用，失败时返回原配置和错误
+func reload(name string, old Config) (Config, error) {
+	config, err := LoadConfig(name)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		return old, err
+	}
+
+	// 校验
+	err = prom.Check(config.Prom)
+	if err != nil {
+		return old, fmt.Errorf("prom: %v", err)
+	}
+	err = alert.Check(config.Alert)
+	if err != nil {
+		return old, err
+	}
+	err = notify.Check(config.Notify)
+	if err != nil {
+		return old, err
+	}
+
+	// 应用，用户文件在替换前校验
+	err = user.Init(config.Http.Users, config.Http.Roles)
+	if err != nil {
+		return old, fmt.Errorf("user: %v", err)
+	}
+	if config.Http.Limit != old.Http.Limit {
+		user.InitLimit(config.Http.Limit)
+	}
+	if !reflect.DeepEqual(config.Prom, old.Prom) || !reflect.DeepEqual(config.Metrics, old.Metrics) {
+		err = prom.Init(config.Prom, config.Metrics)
+		if err != nil {
+			return old, fmt.Errorf("prom: %v", err)
+		}
+	}
+	// 告警和通知重新初始化会重置状态（告警持续时间、通知频率限制），只在配置修改后重新初始化
+	if !reflect.DeepEqual(config.Alert, old.Alert) {
+		alert.Init(config.Alert)
+	}
+	if !reflect.DeepEqual(config.Notify, old.Notify) {
+		notify.Init(config.Notify)
+	}
+
+	for _, key := range restartKeys(old, config) {
+		log.Printf("reload config: %s changed, restart to take effect\n", key)
+	}
+	return config, nil
+}
+
+// 已修改但需要重启后生效的配置
+func restartKeys(old, config Config) []string {
+	var keys []string
+	for _, item := range []struct {
+		key      string
+		old, new any
+	}{
+		{"[http] host", old.Http.Host, config.Http.Host},
+		{"[http] port", old.Http.Port, config.Http.Port},
+		{"[http] prefix", old.Http.Prefix, config.Http.Prefix},
+		{"[http] tls_*", old.Http.Tls, config.Http.Tls},
+		{"[http] *_timeout", []time.Duration{old.Http.ReadTimeout, old.Http.ReadHeaderTimeout, old.Http.WriteTimeout, old.Http.IdleTimeout, old.Http.ShutdownTimeout},
+			[]time.Duration{config.Http.ReadTimeout, config.Http.ReadHeaderTimeout, config.Http.WriteTimeout, config.Http.IdleTimeout, config.Http.ShutdownTimeout}},
+		{"[session]", old.Session, config.Session},
+		{"[oidc]", old.Http.Oidc, config.Http.Oidc},
+		{"[proxy]", old.Http.Proxy, config.Http.Proxy},
+		{"[api]", old.Http.Tokens, config.Http.Tokens},
+	} {
+		if !reflect.DeepEqual(item.old, item.new) {
+			keys = append(keys, item.key)
+		}
+	}
+	return keys
+}
+
+// 配置文件、指标目录文件和用户文件的修改时间，文件不存在时为零值
+func fileModTimes(name string, config Config) []time.Time {
+	var arr []time.Time
+	for _, file := range []string{name, config.MetricFile, config.Http.Users} {
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		arr = append(arr, modTime)
+	}
+	return arr
+}
@@ -0,0 +1,116 @@
+// @author xiangqian
+// @date 2025/09/04 21:30
+package main
+
+import (
+	"fmt"
+	"gmon/pkg/prom"
+	"gmon/pkg/user"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRestartKeys(t *testing.T) {
+	var old = Config{Http: Http{Port: 59090, Users: "users.ini", WriteTimeout: time.Minute}}
+
+	// 可以在运行时生效的配置
+	var config = old
+	config.Http.Users = "users2.ini"
+	if keys := restartKeys(old, config); len(keys) != 0 {
+		t.Fatalf("restart keys: %v", keys)
+	}
+
+	// 需要重启后生效的配置
+	config.Http.Port = 8080
+	config.Http.WriteTimeout = time.Second
+	config.Session.MaxCount = 10
+	if keys := restartKeys(old, config); !slices.Equal(keys, []string{"[http] port", "[http] *_timeout", "[session]"}) {
+		t.Fatalf("restart keys: %v", keys)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.ini")
+	if err := user.Add(users, "admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	metric, err := filepath.Abs("metric.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未监听的端口，数据源不可用不影响加载
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	name := filepath.Join(dir, "config.ini")
+	write := func(httpPort, source string, delay time.Duration) {
+		t.Helper()
+		content := fmt.Sprintf("[http]\nport = %s\nusers = %s\n\n[login]\ndelay = %s\n\n[prom]\nmetric = %s\n\n[prom.%s]\nhost = 127.0.0.1\nport = %d\n",
+			httpPort, users, delay, metric, source, port)
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(name, passwd, source string, delay time.Duration) {
+		t.Helper()
+		if _, ok := user.Authenticate(name, passwd); !ok {
+			t.Errorf("user %s not applied", name)
+		}
+		if clients := prom.Clients(); len(clients) != 1 || clients[0].Name() != source {
+			t.Errorf("prom source %s not applied", source)
+		}
+		if _, d := user.Failed("192.0.2.1", "nobody"); d != delay {
+			t.Errorf("login delay: %v, want %v", d, delay)
+		}
+		user.Succeeded("192.0.2.1", "nobody")
+	}
+
+	write("59090", "a", 7*time.Second)
+	config, err := reload(name, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("admin", "secret", "a", 7*time.Second)
+
+	// 修改用户、登录失败限制和数据源后生效
+	if err = user.Add(users, "bob", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	write("59090", "b", 3*time.Second)
+	config, err = reload(name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("bob", "secret", "b", 3*time.Second)
+
+	// 无效的配置被拒绝，继续使用原配置
+	write("5909o", "c", time.Second)
+	got, err := reload(name, config)
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	if got.Http.Port != 59090 || got.Prom[0].Name != "b" {
+		t.Fatalf("old config not kept: %+v", got)
+	}
+	check("bob", "secret", "b", 3*time.Second)
+
+	// 用户文件无效时同样被拒绝
+	write("59090", "c", time.Second)
+	if err = os.WriteFile(users, []byte("[bob]\npasswd = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reload(name, config); err == nil {
+		t.Fatal("invalid users file accepted")
+	}
+	check("bob", "secret", "b", 3*time.Second)
+}
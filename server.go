@@ -0,0 +1,114 @@
+// @author xiangqian
+// @date 2025/09/02 20:16
+package main
+
+import (
+	"context"
+	"errors"
+	"gmon/handler"
+	"gmon/pkg/xhttp"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// 启动服务器和后台任务，收到 SIGINT、SIGTERM 信号后优雅关闭：
+// 1. 停止接受新连接，通知 /event 连接的客户端稍后重连
+// 2. 在 shutdown_timeout 内等待处理中的请求完成，超时后强制关闭连接
+// 3. 停止后台任务，并等待其退出
+func serve(config Http, tasks ...func(context.Context)) {
+	tlsConfig, err := xhttp.NewTlsConfig(config.Tls)
+	if err != nil {
+		log.Fatalf("init tls: %v\n", err)
+	}
+
+	// 后台任务
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task(ctx)
+		}()
+	}
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))),
+		TLSConfig:         tlsConfig,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout, // 事件流（/event）不受写超时限制
+		IdleTimeout:       config.IdleTimeout,
+	}
+	server.RegisterOnShutdown(handler.Shutdown)
+	var servers = []*http.Server{server}
+
+	// 启动服务器
+	var errCh = make(chan error, 2)
+	go func() {
+		if tlsConfig == nil {
+			log.Printf("Server starting on %s ...\n", server.Addr)
+			errCh <- server.ListenAndServe()
+			return
+		}
+		log.Printf("Server starting on %s (https) ...\n", server.Addr)
+		// 证书由 TLSConfig.GetCertificate 提供
+		errCh <- server.ListenAndServeTLS("", "")
+	}()
+
+	// HTTP 重定向到 HTTPS
+	if tlsConfig != nil && config.Tls.RedirectPort != 0 {
+		redirect := &http.Server{
+			Addr:              net.JoinHostPort(config.Host, strconv.Itoa(int(config.Tls.RedirectPort))),
+			Handler:           xhttp.RedirectHttps(config.Port),
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+		}
+		servers = append(servers, redirect)
+		go func() {
+			log.Printf("Redirect server starting on %s ...\n", redirect.Addr)
+			errCh <- redirect.ListenAndServe()
+		}()
+	}
+
+	// 等待信号
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err = <-errCh:
+		cancel()
+		log.Fatalf("ListenAndServe: %v\n", err)
+	case <-sigCtx.Done():
+	}
+	// 再次收到信号时立即退出
+	stop()
+
+	log.Printf("Server shutting down ...\n")
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer shutdownCancel()
+	for _, server := range servers {
+		err = server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("shutdown: %v\n", err)
+			server.Close()
+		}
+	}
+	for range servers {
+		if err = <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("ListenAndServe: %v\n", err)
+		}
+	}
+
+	// 停止后台任务
+	cancel()
+	wg.Wait()
+	log.Printf("Server stopped\n")
+}
@@ -0,0 +1,447 @@
+// @author xiangqian
+// @date 2025/09/06 18:22
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"gmon/pkg/alert"
+	"gmon/pkg/notify"
+	"gmon/pkg/user"
+	"gmon/pkg/xhttp"
+	pkg_ini "gopkg.in/ini.v1"
+	"io/fs"
+	pkg_http "net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 配置校验：一次报告所有问题，每个问题都带有位置（文件:行号，或覆盖该配置项的环境变量、命令行参数），便于在 CI 中检查配置。
+// 加载配置时校验小节、配置项及其值的格式（拼写错误的配置项、无效的端口和时长等），无效的值使用默认值；
+// 再由 Config.Validate 校验配置项的取值、配置项之间的关系和指标目录文件，与格式问题一起报告
+
+// 配置项类型
+type kind int
+
+const (
+	kindString     kind = iota // 字符串
+	kindPort                   // 端口：0-65535
+	kindDuration               // 时长，如 10s、5m
+	kindBool                   // 布尔值
+	kindInt                    // 整数
+	kindFloat                  // 浮点数
+	kindTlsVersion             // TLS 版本：1.2、1.3
+	kindSameSite               // Cookie SameSite 属性：lax、strict、none
+	kindCidrs                  // 网段，多个以逗号分隔
+)
+
+// 配置项定义：小节 -> 配置项 -> 类型，[prom.<名称>]、[alert.<名称>] 小节分别使用 prom.*、alert.* 的定义
+var schema = map[string]map[string]kind{
+	"http": {
+		"host": kindString, "port": kindPort, "prefix": kindString, "users": kindString,
+		"read_timeout": kindDuration, "read_header_timeout": kindDuration, "write_timeout": kindDuration,
+		"idle_timeout": kindDuration, "shutdown_timeout": kindDuration,
+		"tls_cert": kindString, "tls_key": kindString, "tls_min_version": kindTlsVersion,
+		"tls_client_ca": kindString, "tls_redirect_port": kindPort,
+	},
+	"login": {"max_failures": kindInt, "delay": kindDuration, "max_delay": kindDuration, "lockout": kindDuration},
+	"oidc": {
+		"name": kindString, "issuer": kindString, "client_id": kindString, "client_secret": kindString,
+		"redirect_url": kindString, "scopes": kindString, "user_claim": kindString, "role_claim": kindString,
+		"admin_groups": kindString, "viewer_groups": kindString,
+	},
+	"api":   {"tokens": kindString},
+	"proxy": {"header": kindString, "cidrs": kindCidrs},
+	"session": {
+		"store": kindString, "dir": kindString, "max_age": kindDuration, "max_count": kindInt,
+		"cookie_secure": kindBool, "cookie_same_site": kindSameSite,
+	},
+	"prom": {
+		"scheme": kindString, "host": kindString, "port": kindPort, "path": kindString, "metric": kindString,
+		"ca_file": kindString, "cert_file": kindString, "key_file": kindString, "insecure_skip_verify": kindBool,
+		"user": kindString, "passwd": kindString, "token": kindString, "token_file": kindString,
+	},
+	"prom.*": {
+		"scheme": kindString, "host": kindString, "port": kindPort, "path": kindString,
+		"ca_file": kindString, "cert_file": kindString, "key_file": kindString, "insecure_skip_verify": kindBool,
+		"user": kindString, "passwd": kindString, "token": kindString, "token_file": kindString,
+	},
+	"webhook": {"urls": kindString, "retries": kindInt, "backoff": kindDuration, "timeout": kindDuration},
+	"smtp": {
+		"host": kindString, "port": kindPort, "tls": kindString, "user": kindString, "passwd": kindString,
+		"from": kindString, "to": kindString, "interval": kindDuration, "timeout": kindDuration,
+	},
+	"alert":   {"interval": kindDuration},
+	"alert.*": {"type": kindString, "metric": kindString, "op": kindString, "threshold": kindFloat, "for": kindDuration},
+}
+
+// 已移除的配置项 -> 提示
+var removedKeys = map[string]string{
+	locKey("http", "user"):   "removed, users are stored in the users file, add one with: gmon add-user <name>",
+	locKey("http", "passwd"): "removed, users are stored in the users file, add one with: gmon add-user <name>",
+}
+
+// 小节和配置项的正则表达式，用于定位行号
+var (
+	sectionRegexp = regexp.MustCompile(`^\s*\[\s*([^\]]+?)\s*\]`)
+	keyRegexp     = regexp.MustCompile("^\\s*([^#;=:\\s`][^=:]*?)\\s*[=:]")
+)
+
+// 配置项位置
+type locator struct {
+	name    string            // 配置文件
+	lines   map[string]int    // 小节或配置项 -> 行号
+	sources map[string]string // 被环境变量或命令行参数覆盖的配置项 -> 来源
+}
+
+// 扫描配置文件，记录小节和配置项的行号
+func newLocator(name string, data []byte) locator {
+	var loc = locator{name: name, lines: make(map[string]int), sources: make(map[string]string)}
+	var section = pkg_ini.DefaultSection
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if m := sectionRegexp.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			if _, ok := loc.lines[locKey(section, "")]; !ok {
+				loc.lines[locKey(section, "")] = n
+			}
+			continue
+		}
+		if m := keyRegexp.FindStringSubmatch(line); m != nil {
+			loc.lines[locKey(section, m[1])] = n
+		}
+	}
+	return loc
+}
+
+func locKey(section, key string) string {
+	return section + "\x00" + key
+}
+
+// 配置项的位置，配置项不存在时为所在小节的位置
+func (loc locator) position(section, key string) string {
+	if source, ok := loc.sources[locKey(section, key)]; ok {
+		return source
+	}
+	if n, ok := loc.lines[locKey(section, key)]; ok {
+		return fmt.Sprintf("%s:%d", loc.name, n)
+	}
+	if n, ok := loc.lines[locKey(section, "")]; ok {
+		return fmt.Sprintf("%s:%d", loc.name, n)
+	}
+	return loc.name
+}
+
+// 带位置的配置错误
+func (loc locator) errorf(section, key string, format string, args ...any) error {
+	var name = fmt.Sprintf("[%s]", section)
+	if key != "" {
+		name = fmt.Sprintf("[%s] %s", section, key)
+	}
+	return fmt.Errorf("%s: %s: %s", loc.position(section, key), name, fmt.Sprintf(format, args...))
+}
+
+// 校验小节、配置项及其值的格式
+func checkKeys(file *pkg_ini.File, loc locator) error {
+	var errs []error
+	for _, section := range file.Sections() {
+		name := section.Name()
+		if name == pkg_ini.DefaultSection {
+			for _, key := range section.Keys() {
+				errs = append(errs, loc.errorf(name, key.Name(), "key outside of any section"))
+			}
+			continue
+		}
+
+		// [role] 小节中每个键为用户名
+		if name == "role" {
+			for _, key := range section.Keys() {
+				if _, err := xhttp.ParseRole(key.String()); err != nil {
+					errs = append(errs, loc.errorf(name, key.Name(), "%v", err))
+				}
+			}
+			continue
+		}
+
+		keys, ok := schema[name]
+		if !ok {
+			if i := strings.Index(name, "."); i > 0 {
+				keys, ok = schema[name[:i]+".*"]
+			}
+		}
+		if !ok {
+			errs = append(errs, loc.errorf(name, "", "unknown section"))
+			continue
+		}
+		for _, key := range section.Keys() {
+			if hint, ok := removedKeys[locKey(name, key.Name())]; ok {
+				errs = append(errs, loc.errorf(name, key.Name(), "%s", hint))
+				continue
+			}
+			k, ok := keys[key.Name()]
+			if !ok {
+				errs = append(errs, loc.errorf(name, key.Name(), "unknown key"))
+				continue
+			}
+			if err := checkValue(k, strings.TrimSpace(key.String())); err != nil {
+				errs = append(errs, loc.errorf(name, key.Name(), "%v", err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 校验配置项的值，空值表示使用默认值
+func checkValue(k kind, value string) error {
+	if value == "" {
+		return nil
+	}
+	var err error
+	switch k {
+	case kindPort:
+		_, err = strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid port %q, must be 0-65535", value)
+		}
+	case kindDuration:
+		_, err = time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, e.g. 10s, 5m, 1h", value)
+		}
+	case kindBool:
+		_, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q, must be true or false", value)
+		}
+	case kindInt:
+		_, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+	case kindFloat:
+		_, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+	case kindTlsVersion:
+		_, err = xhttp.ParseTlsVersion(value)
+		return err
+	case kindSameSite:
+		switch strings.ToLower(value) {
+		case "lax", "strict", "none":
+		default:
+			return fmt.Errorf("invalid value %q, must be lax, strict or none", value)
+		}
+	case kindCidrs:
+		_, err = xhttp.ParseCidrs(strings.Split(value, ","))
+		return err
+	}
+	return nil
+}
+
+// Validate 校验配置项的取值和配置项之间的关系，返回所有问题
+func (config Config) Validate() error {
+	var loc = config.locator
+	var errs []error
+	add := func(section, key, format string, args ...any) {
+		errs = append(errs, loc.errorf(section, key, format, args...))
+	}
+
+	// 小节、配置项及其值的格式
+	if config.keyErr != nil {
+		errs = append(errs, config.keyErr)
+	}
+
+	// [http]
+	var http = config.Http
+	if http.Port == 0 {
+		add("http", "port", "required")
+	}
+	if http.Tls.RedirectPort != 0 && http.Tls.RedirectPort == http.Port {
+		add("http", "tls_redirect_port", "must differ from port %d", http.Port)
+	}
+	if http.Prefix != "" && (!strings.HasPrefix(http.Prefix, "/") || strings.HasSuffix(http.Prefix, "/") || strings.ContainsAny(http.Prefix, " ?#")) {
+		add("http", "prefix", "invalid prefix %q, must start with / and must not end with /, e.g. /gmon", http.Prefix)
+	}
+	if (http.Tls.Cert == "") != (http.Tls.Key == "") {
+		add("http", "tls_cert", "tls_cert and tls_key must be set together")
+	}
+	if !http.Tls.Enabled() && http.Tls.ClientCa != "" {
+		add("http", "tls_client_ca", "requires tls_cert and tls_key")
+	}
+	if !http.Tls.Enabled() && http.Tls.RedirectPort != 0 {
+		add("http", "tls_redirect_port", "requires tls_cert and tls_key")
+	}
+	for _, item := range [][2]string{{"tls_cert", http.Tls.Cert}, {"tls_key", http.Tls.Key}, {"tls_client_ca", http.Tls.ClientCa}} {
+		if err := checkFile(item[1]); err != nil {
+			add("http", item[0], "%v", err)
+		}
+	}
+
+	// 用户文件，[role] 中不存在的用户在加载用户文件时记录警告日志
+	users, err := user.Load(http.Users)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		add("http", "users", "%s: not found, create the first user with: gmon add-user <name>", http.Users)
+	case err != nil:
+		add("http", "users", "%v", err)
+	case len(users) == 0:
+		add("http", "users", "%s: no user, add one with: gmon add-user <name>", http.Users)
+	}
+
+	// [login]
+	if limit := http.Limit; limit.MaxFailures < 0 || limit.Delay < 0 || limit.MaxDelay < 0 || limit.Lockout < 0 {
+		add("login", "", "max_failures, delay, max_delay and lockout must not be negative")
+	}
+
+	// [proxy]
+	if http.Proxy.Header != "" && len(http.Proxy.Cidrs) == 0 {
+		add("proxy", "cidrs", "required when header is set")
+	}
+
+	// [session]
+	var session = config.Session
+	if session.Store != "memory" && session.Store != "file" {
+		add("session", "store", "invalid store %q, must be memory or file", session.Store)
+	}
+	if session.MaxAge <= 0 {
+		add("session", "max_age", "must be positive")
+	}
+	if session.MaxCount <= 0 {
+		add("session", "max_count", "must be positive")
+	}
+	// 浏览器要求 SameSite=None 的 Cookie 必须同时设置 Secure
+	if session.CookieSameSite == pkg_http.SameSiteNoneMode && !session.CookieSecure {
+		add("session", "cookie_same_site", "none requires cookie_secure = true")
+	}
+
+	// [oidc]
+	if oidc := http.Oidc; oidc.Issuer != "" {
+		if oidc.ClientId == "" {
+			add("oidc", "client_id", "required when issuer is set")
+		}
+		if oidc.RedirectUrl == "" {
+			add("oidc", "redirect_url", "required when issuer is set")
+		}
+	}
+
+	// [prom]
+	if len(config.Prom) == 0 {
+		add("prom", "", "no prometheus data source configured")
+	}
+	for _, c := range config.Prom {
+		var section = "prom"
+		if c.Name != "default" {
+			section = "prom." + c.Name
+		}
+		if c.Scheme != "http" && c.Scheme != "https" {
+			add(section, "scheme", "invalid scheme %q, must be http or https", c.Scheme)
+		}
+		if c.Host == "" {
+			add(section, "host", "required")
+		} else if strings.ContainsAny(c.Host, "/?#@") {
+			add(section, "host", "invalid host %q, use scheme, port and path for the other parts of the address", c.Host)
+		}
+		if c.Port == 0 {
+			add(section, "port", "required")
+		}
+		if c.Path != "" && !strings.HasPrefix(c.Path, "/") {
+			add(section, "path", "invalid path %q, must start with /", c.Path)
+		}
+
+		// 认证
+		if (c.User == "") != (c.Passwd == "") {
+			add(section, "user", "user and passwd must be set together")
+		}
+		if c.User != "" && (c.Token != "" || c.TokenFile != "") {
+			add(section, "token", "basic auth (user, passwd) and bearer token (token, token_file) are mutually exclusive")
+		}
+		if (c.CertFile == "") != (c.KeyFile == "") {
+			add(section, "cert_file", "cert_file and key_file must be set together")
+		}
+		if c.Scheme == "http" && (c.CaFile != "" || c.CertFile != "" || c.InsecureSkipVerify) {
+			add(section, "scheme", "tls options require scheme = https")
+		}
+		for _, item := range [][2]string{{"ca_file", c.CaFile}, {"cert_file", c.CertFile}, {"key_file", c.KeyFile}, {"token_file", c.TokenFile}} {
+			if err := checkFile(item[1]); err != nil {
+				add(section, item[0], "%v", err)
+			}
+		}
+	}
+
+	// 指标目录
+	if data, err := os.ReadFile(config.MetricFile); err != nil {
+		add("prom", "metric", "%v", err)
+	} else if err = checkMetrics(config.MetricFile, data); err != nil {
+		errs = append(errs, err)
+	} else if len(config.Metrics) == 0 {
+		add("prom", "metric", "%s: no metric", config.MetricFile)
+	}
+
+	// [alert.<name>]
+	for _, rule := range config.Alert.Rules {
+		if err := alert.Check(alert.Config{Rules: []alert.Rule{rule}}); err != nil {
+			add("alert."+rule.Name, "", "%v", err)
+		}
+	}
+
+	// [smtp]
+	if err := notify.Check(config.Notify); err != nil {
+		add("smtp", "", "%v", err)
+	}
+	if smtp := config.Notify.Smtp; smtp.Host != "" && smtp.Passwd != "" && smtp.User == "" {
+		add("smtp", "user", "required when passwd is set")
+	}
+	// net/smtp 的 PLAIN 认证只允许通过加密连接或者连接本机时发送密码
+	if smtp := config.Notify.Smtp; smtp.Host != "" && smtp.User != "" && smtp.Tls == notify.TlsNone &&
+		smtp.Host != "localhost" && smtp.Host != "127.0.0.1" && smtp.Host != "::1" {
+		add("smtp", "tls", "authentication requires tls = tls or starttls unless host is localhost")
+	}
+	return errors.Join(errs...)
+}
+
+// 校验指标目录文件：每个小节为一个指标系列
+func checkMetrics(name string, data []byte) error {
+	file, err := pkg_ini.Load(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	var loc = newLocator(name, data)
+	var errs []error
+	for _, section := range file.Sections() {
+		// 跳过默认小节
+		if section.Name() == pkg_ini.DefaultSection {
+			continue
+		}
+		for _, key := range []string{"job", "name", "expr"} {
+			if strings.TrimSpace(section.Key(key).String()) == "" {
+				errs = append(errs, loc.errorf(section.Name(), key, "required"))
+			}
+		}
+		if axis := strings.TrimSpace(section.Key("axis").MustString("left")); axis != "left" && axis != "right" {
+			errs = append(errs, loc.errorf(section.Name(), "axis", "invalid axis %q, must be left or right", axis))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 校验文件是否存在，文件名为空时不校验
+func checkFile(name string) error {
+	if name == "" {
+		return nil
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: is a directory", name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// @author xiangqian
+// @date 2025/09/06 21:40
+package main
+
+import (
+	"gmon/pkg/user"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 写入配置文件，指标目录文件使用仓库中的文件
+func writeConfig(t *testing.T, content string) string {
+	name := filepath.Join(t.TempDir(), "config.ini")
+	err := os.WriteFile(name, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadConfigCheckKeys(t *testing.T) {
+	metric := filepath.Join(t.TempDir(), "metric.ini")
+	if err := os.WriteFile(metric, []byte("[go.cpu]\njob  = go\nname = cpu\naxis = top\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	name := writeConfig(t, `[http]
+port = 5909o
+read_timout = 30s
+user = admin
+tls_min_version = 1.1
+
+[prom]
+host = localhost
+port = 9090
+metric = `+metric+`
+
+[alert.down]
+type = down
+for  = 60
+
+[session]
+max_age = -1h
+`)
+	// 加载时不校验，所有问题由 Validate 一起报告
+	config, err := LoadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Validate()
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, s := range []string{
+		name + `:2: [http] port: invalid port "5909o"`,
+		name + `:3: [http] read_timout: unknown key`,
+		name + `:4: [http] user: removed, users are stored in the users file, add one with: gmon add-user <name>`,
+		name + `:5: [http] tls_min_version: tls version 1.1 is deprecated`,
+		name + `:14: [alert.down] for: invalid duration "60"`,
+		name + `:17: [session] max_age: must be positive`,
+		metric + `:1: [go.cpu] expr: required`,
+		metric + `:4: [go.cpu] axis: invalid axis "top"`,
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("missing %q in:\n%v", s, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	users := filepath.Join(t.TempDir(), "users.ini")
+	if err := user.Add(users, "admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	name := writeConfig(t, `[http]
+port   = 59090
+prefix = gmon/
+users  = `+users+`
+
+[prom]
+host   = localhost
+port   = 9090
+user   = bob
+metric = metric.ini
+
+[smtp]
+host = smtp.example.com
+user = gmon
+from = gmon@example.com
+to   = ops@example.com
+`)
+	t.Setenv("GMON_PROM_SCHEME", "ftp")
+	config, err := LoadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Validate()
+	if err == nil {
+		t.Fatal("invalid config accepted")
+	}
+	for _, s := range []string{
+		name + `:3: [http] prefix: invalid prefix "gmon/"`,
+		`GMON_PROM_SCHEME: [prom] scheme: invalid scheme "ftp"`,
+		name + `:9: [prom] user: user and passwd must be set together`,
+		name + `:12: [smtp] tls: authentication requires tls = tls or starttls unless host is localhost`,
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("missing %q in:\n%v", s, err)
+		}
+	}
+
+	// 有效配置
+	t.Setenv("GMON_PROM_SCHEME", "http")
+	t.Setenv("GMON_PROM_USER", "")
+	t.Setenv("GMON_HTTP_PREFIX", "/gmon")
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(name, []byte(strings.Replace(string(data), "smtp.example.com", "localhost", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err = LoadConfig(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}